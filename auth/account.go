@@ -0,0 +1,620 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAccountNotFound    = errors.New("account not found")
+	ErrAccountExists      = errors.New("account already exists")
+	ErrAccountConflict    = errors.New("account was modified concurrently")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrMFARequired        = errors.New("multi-factor authentication code required")
+	ErrInvalidMFACode     = errors.New("invalid multi-factor authentication code")
+	ErrTOTPNotEnrolled    = errors.New("totp not enrolled")
+	ErrTOTPEnabled        = errors.New("totp already enabled")
+)
+
+// Account is a local account logging in with a password and, optionally, a TOTP code.
+// The TOTP secret is stored as is, so the table must be protected like any other credential store.
+type Account struct {
+	ID           string `gorm:"primaryKey"`
+	Username     string `gorm:"uniqueIndex;not null"`
+	PasswordHash string `gorm:"not null"`
+	// TOTPSecret is set when enrolment starts and TOTPEnabled once a first code was confirmed.
+	TOTPSecret   string
+	TOTPEnabled  bool
+	TOTPLastStep int64
+	// RecoveryCodes is a space-separated list of the password hashes of the unused recovery codes.
+	RecoveryCodes     string
+	PasswordChangedAt time.Time
+	// Version is incremented by every update, so that concurrent logins cannot use the same code twice.
+	Version   int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (Account) TableName() string {
+	return "auth_accounts"
+}
+
+// AccountStore keeps accounts. Get and GetByUsername must return ErrAccountNotFound for an unknown account,
+// Create must return ErrAccountExists for a taken username, and Update must return ErrAccountConflict
+// when the stored version is not the one of the account anymore, and increment it otherwise.
+type AccountStore interface {
+	Create(ctx context.Context, account *Account) error
+	Get(ctx context.Context, id string) (*Account, error)
+	GetByUsername(ctx context.Context, username string) (*Account, error)
+	Update(ctx context.Context, account *Account) error
+}
+
+// AccountsConfig configures Accounts. Every field is optional.
+type AccountsConfig struct {
+	// Hasher defaults to Argon2id with the default parameters.
+	Hasher *PasswordHasher
+	// Policy defaults to a minimum length of 12 characters. Its MaxBytes is capped to what Hasher accepts.
+	Policy *PasswordPolicy
+	// Throttle defaults to a MemoryLoginThrottle with a 15 minute window.
+	Throttle        LoginThrottle
+	ThrottleOptions *ThrottleOptions
+	// TOTPIssuer names the service in authenticator apps.
+	TOTPIssuer string
+}
+
+// LoginRequest are the credentials of a login. Code is a TOTP code or a recovery code, only needed
+// once the account enabled TOTP. IP is the address of the client, used for throttling.
+type LoginRequest struct {
+	Username string
+	Password string
+	Code     string
+	IP       string
+}
+
+// TOTPEnrolment is the secret an authenticator is set up with, and its otpauth:// URI to show as a QR code.
+type TOTPEnrolment struct {
+	Secret string
+	URI    string
+}
+
+// Accounts registers local accounts and logs them in.
+type Accounts struct {
+	store     AccountStore
+	hasher    *PasswordHasher
+	policy    *PasswordPolicy
+	throttle  LoginThrottle
+	limits    ThrottleOptions
+	issuer    string
+	dummyHash string
+}
+
+func NewAccounts(store AccountStore, c AccountsConfig) (*Accounts, error) {
+	a := &Accounts{
+		store:    store,
+		hasher:   c.Hasher,
+		policy:   c.Policy,
+		throttle: c.Throttle,
+		limits:   c.ThrottleOptions.withDefaults(),
+		issuer:   c.TOTPIssuer,
+	}
+
+	if a.hasher == nil {
+		hasher, err := NewPasswordHasher(nil)
+		if err != nil {
+			return nil, err
+		}
+		a.hasher = hasher
+	}
+	if a.policy == nil {
+		a.policy = &PasswordPolicy{}
+	}
+	if limit := a.hasher.MaxPasswordBytes(); limit > 0 && (a.policy.MaxBytes <= 0 || a.policy.MaxBytes > limit) {
+		// passwords the hasher rejects must fail the policy rather than the registration
+		policy := *a.policy
+		policy.MaxBytes = limit
+		a.policy = &policy
+	}
+	if a.throttle == nil {
+		a.throttle = NewMemoryLoginThrottle(0)
+	}
+
+	// unknown usernames are verified against this hash, so that they take as long as known ones
+	dummyHash, err := a.hasher.Hash("dummy password")
+	if err != nil {
+		return nil, err
+	}
+	a.dummyHash = dummyHash
+
+	return a, nil
+}
+
+// Register creates an account. The password must meet the policy, see PasswordPolicy.Check.
+func (a *Accounts) Register(ctx context.Context, username string, password string) (*Account, error) {
+	username = normalizeUsername(username)
+	if username == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := a.policy.Check(password, username); err != nil {
+		return nil, err
+	}
+
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := newTokenID()
+	if err != nil {
+		return nil, fmt.Errorf("registering account failed: %w", err)
+	}
+
+	now := time.Now().UTC()
+	account := &Account{
+		ID:                id,
+		Username:          username,
+		PasswordHash:      hash,
+		PasswordChangedAt: now,
+	}
+
+	if err = a.store.Create(ctx, account); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// Get returns an account.
+func (a *Accounts) Get(ctx context.Context, id string) (*Account, error) {
+	return a.store.Get(ctx, id)
+}
+
+// Login checks the credentials of a request and returns the account they belong to. It returns a *ThrottledError
+// while the account or the IP has too many recent failures, ErrInvalidCredentials for a wrong username or password,
+// and ErrMFARequired when the password is right but the account needs a code that was not given.
+// Password hashes made with outdated parameters are replaced on success.
+func (a *Accounts) Login(ctx context.Context, req LoginRequest) (*Account, error) {
+	username := normalizeUsername(req.Username)
+	keys := map[string]int{"account:" + username: a.limits.AccountMaxFailures}
+	if req.IP != "" {
+		keys["ip:"+req.IP] = a.limits.IPMaxFailures
+	}
+
+	var attempted []string
+	var retryAfter time.Duration
+	for key, maxFailures := range keys {
+		blocked, err := a.throttle.Attempt(ctx, key, maxFailures)
+		if err != nil {
+			return nil, errors.Join(err, a.release(ctx, attempted))
+		}
+		if blocked > retryAfter {
+			retryAfter = blocked
+		}
+		if blocked == 0 {
+			attempted = append(attempted, key)
+		}
+	}
+	if retryAfter > 0 {
+		if err := a.release(ctx, attempted); err != nil {
+			return nil, err
+		}
+
+		return nil, &ThrottledError{RetryAfter: retryAfter}
+	}
+
+	account, err := a.login(ctx, username, req)
+	if errors.Is(err, ErrInvalidCredentials) || errors.Is(err, ErrInvalidMFACode) {
+		// the attempt stays counted as a failure
+		return nil, err
+	}
+	if releaseErr := a.release(ctx, attempted); releaseErr != nil {
+		return nil, releaseErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the IP is not reset, or one valid account would let an attacker try others indefinitely
+	if err = a.throttle.Reset(ctx, "account:"+username); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (a *Accounts) login(ctx context.Context, username string, req LoginRequest) (*Account, error) {
+	account, err := a.store.GetByUsername(ctx, username)
+	if err != nil && !errors.Is(err, ErrAccountNotFound) {
+		return nil, err
+	}
+
+	hash := a.dummyHash
+	if account != nil {
+		hash = account.PasswordHash
+	}
+
+	match, rehash, err := a.hasher.Verify(hash, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || !match {
+		return nil, ErrInvalidCredentials
+	}
+
+	if account.TOTPEnabled {
+		if req.Code == "" {
+			return nil, ErrMFARequired
+		}
+		if !a.useCode(account, req.Code) {
+			return nil, ErrInvalidMFACode
+		}
+	}
+
+	if rehash {
+		if account.PasswordHash, err = a.hasher.Hash(req.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	if rehash || account.TOTPEnabled {
+		err = a.store.Update(ctx, account)
+		if errors.Is(err, ErrAccountConflict) && account.TOTPEnabled {
+			// a concurrent login got in first, possibly with the same code
+			return nil, ErrInvalidMFACode
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return account, nil
+}
+
+// ChangePassword replaces the password of an account after checking the current one. Existing sessions stay valid;
+// revoke them with Sessions.RevokeAll if they should not.
+func (a *Accounts) ChangePassword(ctx context.Context, id string, current string, password string) error {
+	account, err := a.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	match, _, err := a.hasher.Verify(account.PasswordHash, current)
+	if err != nil {
+		return err
+	}
+	if !match {
+		return ErrInvalidCredentials
+	}
+
+	return a.setPassword(ctx, account, password)
+}
+
+// SetPassword replaces the password of an account without checking the current one, e.g. after a reset by email.
+func (a *Accounts) SetPassword(ctx context.Context, id string, password string) error {
+	account, err := a.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return a.setPassword(ctx, account, password)
+}
+
+func (a *Accounts) setPassword(ctx context.Context, account *Account, password string) error {
+	if err := a.policy.Check(password, account.Username); err != nil {
+		return err
+	}
+
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	account.PasswordHash = hash
+	account.PasswordChangedAt = time.Now().UTC()
+
+	return a.store.Update(ctx, account)
+}
+
+// EnrollTOTP starts the TOTP enrolment of an account with a new secret. TOTP is only enabled once ConfirmTOTP
+// got a code from the authenticator, so an abandoned enrolment does not lock the user out.
+func (a *Accounts) EnrollTOTP(ctx context.Context, id string) (*TOTPEnrolment, error) {
+	account, err := a.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account.TOTPEnabled {
+		return nil, ErrTOTPEnabled
+	}
+
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	account.TOTPSecret = secret
+	if err = a.store.Update(ctx, account); err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrolment{
+		Secret: secret,
+		URI:    TOTPURI(a.issuer, account.Username, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables TOTP for an account with a code of the enrolled authenticator and returns its recovery codes,
+// which are shown once.
+func (a *Accounts) ConfirmTOTP(ctx context.Context, id string, code string) ([]string, error) {
+	account, err := a.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if account.TOTPEnabled {
+		return nil, ErrTOTPEnabled
+	}
+	if account.TOTPSecret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	step, ok := ValidateTOTP(account.TOTPSecret, code, 0, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := NewRecoveryCodes(a.hasher)
+	if err != nil {
+		return nil, err
+	}
+
+	account.TOTPEnabled = true
+	account.TOTPLastStep = step
+	account.RecoveryCodes = strings.Join(hashes, " ")
+	if err = a.store.Update(ctx, account); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTOTP turns TOTP off for an account and drops its secret and recovery codes.
+func (a *Accounts) DisableTOTP(ctx context.Context, id string) error {
+	account, err := a.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	account.TOTPSecret = ""
+	account.TOTPEnabled = false
+	account.TOTPLastStep = 0
+	account.RecoveryCodes = ""
+
+	return a.store.Update(ctx, account)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of an account with TOTP enabled and returns the new ones.
+func (a *Accounts) RegenerateRecoveryCodes(ctx context.Context, id string) ([]string, error) {
+	account, err := a.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !account.TOTPEnabled {
+		return nil, ErrTOTPNotEnrolled
+	}
+
+	codes, hashes, err := NewRecoveryCodes(a.hasher)
+	if err != nil {
+		return nil, err
+	}
+
+	account.RecoveryCodes = strings.Join(hashes, " ")
+	if err = a.store.Update(ctx, account); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// useCode checks a TOTP or recovery code and records its use on the account, which must then be updated.
+func (a *Accounts) useCode(account *Account, code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		step, ok := ValidateTOTP(account.TOTPSecret, code, account.TOTPLastStep, time.Now())
+		if ok {
+			account.TOTPLastStep = step
+		}
+
+		return ok
+	}
+
+	code = normalizeRecoveryCode(code)
+	hashes := strings.Fields(account.RecoveryCodes)
+	for i, h := range hashes {
+		if match, _, err := a.hasher.Verify(h, code); err == nil && match {
+			account.RecoveryCodes = strings.Join(append(hashes[:i], hashes[i+1:]...), " ")
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *Accounts) release(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := a.throttle.Release(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// MemoryAccountStore keeps accounts in memory. It only fits a single instance.
+type MemoryAccountStore struct {
+	mu       sync.Mutex
+	accounts map[string]Account
+}
+
+func NewMemoryAccountStore() *MemoryAccountStore {
+	return &MemoryAccountStore{accounts: map[string]Account{}}
+}
+
+func (m *MemoryAccountStore) Create(_ context.Context, account *Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.accounts {
+		if existing.Username == account.Username {
+			return ErrAccountExists
+		}
+	}
+
+	now := time.Now().UTC()
+	account.CreatedAt, account.UpdatedAt = now, now
+	m.accounts[account.ID] = *account
+
+	return nil
+}
+
+func (m *MemoryAccountStore) Get(_ context.Context, id string) (*Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account, ok := m.accounts[id]
+	if !ok {
+		return nil, ErrAccountNotFound
+	}
+
+	return &account, nil
+}
+
+func (m *MemoryAccountStore) GetByUsername(_ context.Context, username string) (*Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, account := range m.accounts {
+		if account.Username == username {
+			return &account, nil
+		}
+	}
+
+	return nil, ErrAccountNotFound
+}
+
+func (m *MemoryAccountStore) Update(_ context.Context, account *Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.accounts[account.ID]
+	if !ok {
+		return ErrAccountNotFound
+	}
+	if stored.Version != account.Version {
+		return ErrAccountConflict
+	}
+
+	account.Version++
+	account.UpdatedAt = time.Now().UTC()
+	m.accounts[account.ID] = *account
+
+	return nil
+}
+
+// GormAccountStore keeps accounts in Postgres.
+type GormAccountStore struct {
+	db *gorm.DB
+}
+
+func NewGormAccountStore(db *gorm.DB) *GormAccountStore {
+	return &GormAccountStore{db: db}
+}
+
+// Migrate creates or updates the table holding accounts.
+func (g *GormAccountStore) Migrate(ctx context.Context) error {
+	return g.db.WithContext(ctx).AutoMigrate(&Account{})
+}
+
+func (g *GormAccountStore) Create(ctx context.Context, account *Account) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Account{}).Where("username = ?", account.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAccountExists
+		}
+
+		err := tx.Create(account).Error
+		if isUniqueViolation(err) {
+			// a concurrent Create took the username since it was counted
+			return ErrAccountExists
+		}
+
+		return err
+	})
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.Is(err, gorm.ErrDuplicatedKey) || errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func (g *GormAccountStore) Get(ctx context.Context, id string) (*Account, error) {
+	return g.take(ctx, "id = ?", id)
+}
+
+func (g *GormAccountStore) GetByUsername(ctx context.Context, username string) (*Account, error) {
+	return g.take(ctx, "username = ?", username)
+}
+
+func (g *GormAccountStore) Update(ctx context.Context, account *Account) error {
+	res := g.db.WithContext(ctx).Model(&Account{}).
+		Where("id = ? AND version = ?", account.ID, account.Version).
+		Updates(map[string]interface{}{
+			"password_hash":       account.PasswordHash,
+			"totp_secret":         account.TOTPSecret,
+			"totp_enabled":        account.TOTPEnabled,
+			"totp_last_step":      account.TOTPLastStep,
+			"recovery_codes":      account.RecoveryCodes,
+			"password_changed_at": account.PasswordChangedAt,
+			"version":             account.Version + 1,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		if _, err := g.Get(ctx, account.ID); err != nil {
+			return err
+		}
+
+		return ErrAccountConflict
+	}
+
+	account.Version++
+
+	return nil
+}
+
+func (g *GormAccountStore) take(ctx context.Context, query string, arg string) (*Account, error) {
+	var account Account
+	err := g.db.WithContext(ctx).Where(query, arg).Take(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
@@ -0,0 +1,353 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/badfan/go-toolkit/tracer"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAPIKeyPrefix     = "key"
+	defaultAPIKeyHeader     = "X-API-Key"
+	defaultLastUsedInterval = time.Minute
+	apiKeyIDLength          = 16
+)
+
+var (
+	// ErrInvalidAPIKey is returned for malformed, unknown, wrong, revoked and expired keys alike,
+	// so that callers learn nothing about existing keys.
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+type apiKeyKey struct{}
+
+// APIKey is an issued API key. Only a hash of its secret is stored.
+type APIKey struct {
+	// ID is the public part of the key, used to look it up.
+	ID    string `gorm:"primaryKey;size:32"`
+	Name  string
+	Owner string `gorm:"index;not null"`
+	Hash  string `gorm:"size:64;not null"`
+	// Scopes is a space-separated list.
+	Scopes     string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (APIKey) TableName() string {
+	return "auth_api_keys"
+}
+
+// ScopeList returns the scopes of the key.
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScopes reports whether the key was granted every scope.
+func (k *APIKey) HasScopes(scopes ...string) bool {
+	granted := k.ScopeList()
+	for _, scope := range scopes {
+		if !contains(granted, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Active reports whether the key is neither revoked nor expired.
+func (k *APIKey) Active() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
+
+type APIKeysConfig struct {
+	// Prefix starts every key, e.g. "acme" gives "acme_<id>_<secret>", which makes leaked keys easy to scan for.
+	// Defaults to "key".
+	Prefix string
+	// LastUsedInterval limits how often the last use of a key is written. Defaults to a minute.
+	LastUsedInterval time.Duration
+}
+
+// APIKeys issues and verifies API keys stored in Postgres.
+type APIKeys struct {
+	db               *gorm.DB
+	prefix           string
+	lastUsedInterval time.Duration
+}
+
+func NewAPIKeys(db *gorm.DB, c APIKeysConfig) *APIKeys {
+	keys := &APIKeys{
+		db:               db,
+		prefix:           c.Prefix,
+		lastUsedInterval: c.LastUsedInterval,
+	}
+	if keys.prefix == "" {
+		keys.prefix = defaultAPIKeyPrefix
+	}
+	if keys.lastUsedInterval <= 0 {
+		keys.lastUsedInterval = defaultLastUsedInterval
+	}
+
+	return keys
+}
+
+// Migrate creates or updates the table holding API keys.
+func (a *APIKeys) Migrate(ctx context.Context) error {
+	return a.db.WithContext(ctx).AutoMigrate(&APIKey{})
+}
+
+// Create issues a key for owner. A zero ttl never expires. The returned plaintext key is shown once
+// and cannot be recovered.
+func (a *APIKeys) Create(ctx context.Context, owner string, name string, scopes []string, ttl time.Duration) (string, *APIKey, error) {
+	return a.create(a.db.WithContext(ctx), owner, name, scopes, ttl)
+}
+
+func (a *APIKeys) create(db *gorm.DB, owner string, name string, scopes []string, ttl time.Duration) (string, *APIKey, error) {
+	id, err := randomID()
+	if err != nil {
+		return "", nil, fmt.Errorf("creating api key failed: %w", err)
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", nil, fmt.Errorf("creating api key failed: %w", err)
+	}
+
+	key := &APIKey{
+		ID:     id,
+		Name:   name,
+		Owner:  owner,
+		Hash:   hashSecret(secret),
+		Scopes: strings.Join(scopes, " "),
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err = db.Create(key).Error; err != nil {
+		return "", nil, fmt.Errorf("creating api key failed: %w", err)
+	}
+
+	return a.prefix + "_" + id + "_" + secret, key, nil
+}
+
+// Verify returns the key matching a plaintext key if it is active, and records its use.
+func (a *APIKeys) Verify(ctx context.Context, plaintext string) (*APIKey, error) {
+	id, secret, ok := a.parse(plaintext)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := a.get(ctx, id)
+	if errors.Is(err, ErrAPIKeyNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.Hash)) != 1 || !key.Active() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > a.lastUsedInterval {
+		if err = a.db.WithContext(ctx).Model(key).Update("last_used_at", now).Error; err != nil {
+			return nil, fmt.Errorf("recording api key use failed: %w", err)
+		}
+	}
+
+	return key, nil
+}
+
+// List returns the keys of an owner, most recent first, including revoked and expired ones.
+func (a *APIKeys) List(ctx context.Context, owner string) ([]APIKey, error) {
+	var keys []APIKey
+	if err := a.db.WithContext(ctx).Where("owner = ?", owner).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Rotate issues a new key with the name, owner and scopes of an existing one, and makes the existing key
+// expire after gracePeriod so that clients can switch over. The new key never expires if the old one did not,
+// and otherwise keeps the old key lifetime.
+func (a *APIKeys) Rotate(ctx context.Context, id string, gracePeriod time.Duration) (string, *APIKey, error) {
+	old, err := a.get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if !old.Active() {
+		return "", nil, ErrInvalidAPIKey
+	}
+
+	var ttl time.Duration
+	if old.ExpiresAt != nil {
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+
+	// both writes are one transaction, so that a failure leaves no new key whose plaintext was never returned
+	var plaintext string
+	var key *APIKey
+	err = a.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if plaintext, key, err = a.create(tx, old.Owner, old.Name, old.ScopeList(), ttl); err != nil {
+			return err
+		}
+
+		expiresAt := time.Now().Add(gracePeriod)
+		if old.ExpiresAt == nil || expiresAt.Before(*old.ExpiresAt) {
+			if err = tx.Model(old).Update("expires_at", expiresAt).Error; err != nil {
+				return fmt.Errorf("expiring rotated api key failed: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return plaintext, key, nil
+}
+
+// Revoke disables a key immediately.
+func (a *APIKeys) Revoke(ctx context.Context, id string) error {
+	res := a.db.WithContext(ctx).Model(&APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return fmt.Errorf("revoking api key failed: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+func (a *APIKeys) get(ctx context.Context, id string) (*APIKey, error) {
+	var key APIKey
+	err := a.db.WithContext(ctx).Where("id = ?", id).Take(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// parse splits "<prefix>_<id>_<secret>". The secret is base64url, so it may itself contain underscores.
+func (a *APIKeys) parse(plaintext string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(plaintext, a.prefix+"_")
+	if !ok {
+		return "", "", false
+	}
+
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != apiKeyIDLength || secret == "" {
+		return "", "", false
+	}
+
+	return id, secret, true
+}
+
+// APIKeyMiddlewareOptions configures APIKeyMiddleware.
+type APIKeyMiddlewareOptions struct {
+	// Header the key is read from. Defaults to "X-API-Key".
+	Header string
+	// Scopes must all be granted to the key.
+	Scopes []string
+}
+
+// APIKeyMiddleware authenticates requests with an API key. The key is put into the request context, see
+// APIKeyFromContext. Requests are rejected with a 401 when the key is missing or invalid and with a 403
+// when it lacks a required scope.
+func APIKeyMiddleware(keys *APIKeys, options *APIKeyMiddlewareOptions) gin.HandlerFunc {
+	var opts APIKeyMiddlewareOptions
+	if options != nil {
+		opts = *options
+	}
+	if opts.Header == "" {
+		opts.Header = defaultAPIKeyHeader
+	}
+
+	return func(ctx *gin.Context) {
+		plaintext := ctx.GetHeader(opts.Header)
+		if plaintext == "" {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing api key"})
+			return
+		}
+
+		key, err := keys.Verify(ctx.Request.Context(), plaintext)
+		if errors.Is(err, ErrInvalidAPIKey) {
+			tracer.AddSpanError(tracer.SpanFromContext(ctx.Request.Context()), err)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+			return
+		}
+		if err != nil {
+			tracer.AddSpanError(tracer.SpanFromContext(ctx.Request.Context()), err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+
+		if !key.HasScopes(opts.Scopes...) {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope, requires: " + strings.Join(opts.Scopes, " ")})
+			return
+		}
+
+		ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), apiKeyKey{}, key))
+		ctx.Next()
+	}
+}
+
+// APIKeyFromContext returns the API key of the authenticated request. Ctx can be a *gin.Context or the request context.
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	if ginCtx, ok := ctx.(*gin.Context); ok {
+		ctx = ginCtx.Request.Context()
+	}
+
+	key, ok := ctx.Value(apiKeyKey{}).(*APIKey)
+	return key, ok
+}
+
+func hashSecret(secret string) string {
+	// secrets are 256 random bits, so a fast hash is as safe as a slow one here
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomID returns a hex ID, which never contains the "_" separating the parts of a key.
+func randomID() (string, error) {
+	b := make([]byte, apiKeyIDLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
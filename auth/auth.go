@@ -1,37 +1,54 @@
 package auth
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
+	"github.com/badfan/go-toolkit/auth/oidc"
 	"github.com/spf13/viper"
 )
 
+const (
+	defaultGoogleTimeout = 30 * time.Second
+	maxGoogleResponse    = 1 << 20
+)
+
+// The Google endpoints, which can be pointed at a test server.
+var (
+	GoogleTokenURL    = "https://oauth2.googleapis.com/token"
+	GoogleUserInfoURL = "https://www.googleapis.com/oauth2/v1/userinfo?alt=json"
+)
+
 type GoogleOAuthToken struct {
 	AccessToken string
 	IDToken     string
+	// RefreshToken is only returned when the login requested offline access.
+	RefreshToken string
+	TokenType    string
+	Scope        string
+	ExpiresIn    time.Duration
 }
 
 type GoogleUserResult struct {
-	Id            string
-	Email         string
-	VerifiedEmail bool
-	Name          string
-	GivenName     string
-	FamilyName    string
-	Picture       string
-	Locale        string
+	Id            string `json:"id"`
+	Email         string `json:"email"`
+	VerifiedEmail bool   `json:"verified_email"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Picture       string `json:"picture"`
+	Locale        string `json:"locale"`
 }
 
-func GetGoogleOAuthToken(code string) (*GoogleOAuthToken, error) {
-	const rootURl = "https://oauth2.googleapis.com/token"
-
+// GetGoogleOAuthToken exchanges an authorization code for tokens, with the client configured in viper.
+// A nil client uses one with a 30 second timeout.
+func GetGoogleOAuthToken(ctx context.Context, client *http.Client, code string) (*GoogleOAuthToken, error) {
 	values := url.Values{}
 	values.Add("grant_type", "authorization_code")
 	values.Add("code", code)
@@ -39,91 +56,88 @@ func GetGoogleOAuthToken(code string) (*GoogleOAuthToken, error) {
 	values.Add("client_secret", viper.GetString("google_oauth_client_secret"))
 	values.Add("redirect_uri", viper.GetString("google_oauth_redirect_url"))
 
-	query := values.Encode()
-
-	req, err := http.NewRequest("POST", rootURl, bytes.NewBufferString(query))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, GoogleTokenURL, strings.NewReader(values.Encode()))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("retrieving google token failed: %w", err)
 	}
-
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	client := http.Client{
-		Timeout: time.Second * 30,
-	}
 
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, err
+	var res struct {
+		AccessToken  string `json:"access_token"`
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		Scope        string `json:"scope"`
+		ExpiresIn    int64  `json:"expires_in"`
 	}
-
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.New("could not retrieve token")
+	if err = doGoogleRequest(client, req, &res); err != nil {
+		return nil, fmt.Errorf("retrieving google token failed: %w", err)
 	}
-
-	var resBody bytes.Buffer
-	_, err = io.Copy(&resBody, res.Body)
-	if err != nil {
-		return nil, err
+	if res.AccessToken == "" {
+		return nil, fmt.Errorf("retrieving google token failed: %w", &oidc.ResponseError{
+			URL:         GoogleTokenURL,
+			StatusCode:  http.StatusOK,
+			Code:        "invalid_response",
+			Description: "no access_token",
+		})
 	}
 
-	var GoogleOAuthTokenRes map[string]interface{}
+	return &GoogleOAuthToken{
+		AccessToken:  res.AccessToken,
+		IDToken:      res.IDToken,
+		RefreshToken: res.RefreshToken,
+		TokenType:    res.TokenType,
+		Scope:        res.Scope,
+		ExpiresIn:    time.Duration(res.ExpiresIn) * time.Second,
+	}, nil
+}
 
-	if err := json.Unmarshal(resBody.Bytes(), &GoogleOAuthTokenRes); err != nil {
-		return nil, err
+// GetGoogleUser gets the profile of the user an access token was issued to. Fields Google omits, e.g. the locale
+// or the family name, are left empty. A nil client uses one with a 30 second timeout.
+func GetGoogleUser(ctx context.Context, client *http.Client, accessToken string) (*GoogleUserResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, GoogleUserInfoURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("retrieving google user failed: %w", err)
 	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
 
-	tokenBody := &GoogleOAuthToken{
-		AccessToken: GoogleOAuthTokenRes["access_token"].(string),
-		IDToken:     GoogleOAuthTokenRes["id_token"].(string),
+	var user GoogleUserResult
+	if err = doGoogleRequest(client, req, &user); err != nil {
+		return nil, fmt.Errorf("retrieving google user failed: %w", err)
 	}
 
-	return tokenBody, nil
+	return &user, nil
 }
 
-func GetGoogleUser(accessToken string, idToken string) (*GoogleUserResult, error) {
-	rootUrl := fmt.Sprintf("https://www.googleapis.com/oauth2/v1/userinfo?alt=json&access_token=%s", accessToken)
-
-	req, err := http.NewRequest("GET", rootUrl, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", idToken))
-
-	client := http.Client{
-		Timeout: time.Second * 30,
+func doGoogleRequest(client *http.Client, req *http.Request, v interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: defaultGoogleTimeout}
 	}
+	req.Header.Set("Accept", "application/json")
 
 	res, err := client.Do(req)
 	if err != nil {
-		return nil, err
-	}
-
-	if res.StatusCode != http.StatusOK {
-		return nil, errors.New("could not retrieve user")
+		return err
 	}
+	defer res.Body.Close()
 
-	var resBody bytes.Buffer
-	_, err = io.Copy(&resBody, res.Body)
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxGoogleResponse))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var GoogleUserRes map[string]interface{}
-
-	if err := json.Unmarshal(resBody.Bytes(), &GoogleUserRes); err != nil {
-		return nil, err
+	// the URL is logged without its query, which may hold secrets
+	endpoint := req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+	if res.StatusCode != http.StatusOK {
+		return oidc.NewResponseError(endpoint, res.StatusCode, body)
 	}
 
-	userBody := &GoogleUserResult{
-		Id:            GoogleUserRes["id"].(string),
-		Email:         GoogleUserRes["email"].(string),
-		VerifiedEmail: GoogleUserRes["verified_email"].(bool),
-		Name:          GoogleUserRes["name"].(string),
-		GivenName:     GoogleUserRes["given_name"].(string),
-		Picture:       GoogleUserRes["picture"].(string),
-		Locale:        GoogleUserRes["locale"].(string),
+	if err = json.Unmarshal(body, v); err != nil {
+		e := oidc.NewResponseError(endpoint, res.StatusCode, body)
+		e.Code = "invalid_response"
+		e.Description = err.Error()
+		return e
 	}
 
-	return userBody, nil
+	return nil
 }
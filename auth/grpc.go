@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/badfan/go-toolkit/tracer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MethodRule is the authorization requirement of gRPC methods.
+type MethodRule struct {
+	// Public methods are served without a token, e.g. health checks.
+	Public bool
+	// Scopes must all be granted to the token.
+	Scopes []string
+	// Roles, when not empty, must contain one of the roles of the token.
+	Roles []string
+}
+
+// GRPCOptions configures the server interceptors.
+type GRPCOptions struct {
+	// Methods maps full method names ("/package.Service/Method") or whole services ("/package.Service/*")
+	// to their rule. Exact names take precedence.
+	Methods map[string]MethodRule
+	// Default is the rule of methods missing from Methods. The zero value only requires a valid token.
+	Default MethodRule
+}
+
+func (o *GRPCOptions) rule(fullMethod string) MethodRule {
+	if o == nil {
+		return MethodRule{}
+	}
+
+	if rule, ok := o.Methods[fullMethod]; ok {
+		return rule
+	}
+
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		if rule, ok := o.Methods[fullMethod[:i]+"/*"]; ok {
+			return rule
+		}
+	}
+
+	return o.Default
+}
+
+// UnaryServerInterceptor authenticates unary calls with a bearer token from the "authorization" metadata,
+// validated with c, and enforces the method rules. Calls fail with Unauthenticated when the token is missing or
+// invalid and with PermissionDenied when the rule is not met. Claims are available through ClaimsFromContext.
+func UnaryServerInterceptor[T any](c TokenConfig, options *GRPCOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorizeCall(ctx, validator[T](c), options.rule(info.FullMethod))
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor[T any](c TokenConfig, options *GRPCOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorizeCall(ss.Context(), validator[T](c), options.rule(info.FullMethod))
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// validateFunc validates a token, returning an error wrapping ErrInvalidToken when it is not acceptable.
+type validateFunc[T any] func(ctx context.Context, token string) (*Claims[T], error)
+
+func validator[T any](c TokenConfig) validateFunc[T] {
+	return func(ctx context.Context, token string) (*Claims[T], error) {
+		return ValidateToken[T](ctx, c, token)
+	}
+}
+
+func authorizeCall[T any](ctx context.Context, validate validateFunc[T], rule MethodRule) (context.Context, error) {
+	if rule.Public {
+		return ctx, nil
+	}
+
+	token := metadataToken(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+
+	claims, err := validate(ctx, token)
+	if errors.Is(err, ErrInvalidToken) {
+		tracer.AddSpanError(tracer.SpanFromContext(ctx), err)
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if err != nil {
+		tracer.AddSpanError(tracer.SpanFromContext(ctx), err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if !claims.HasScopes(rule.Scopes...) {
+		return nil, status.Errorf(codes.PermissionDenied, "insufficient scope, requires: %s", strings.Join(rule.Scopes, " "))
+	}
+	if len(rule.Roles) > 0 && !claims.HasRole(rule.Roles...) {
+		return nil, status.Errorf(codes.PermissionDenied, "missing role, requires one of: %s", strings.Join(rule.Roles, " "))
+	}
+
+	return ContextWithClaims(ctx, claims), nil
+}
+
+func metadataToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, value := range md.Get("authorization") {
+		scheme, token, ok := strings.Cut(value, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+
+	return ""
+}
+
+// serverStream overrides the context of a stream with the authenticated one.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// TokenSource provides the token attached to outgoing calls.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticToken is a TokenSource always returning the same token.
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// UnaryClientInterceptor attaches a bearer token from source to outgoing unary calls.
+func UnaryClientInterceptor(source TokenSource) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := withOutgoingToken(ctx, source)
+		if err != nil {
+			return err
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor attaches a bearer token from source to outgoing streams.
+func StreamClientInterceptor(source TokenSource) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := withOutgoingToken(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func withOutgoingToken(ctx context.Context, source TokenSource) (context.Context, error) {
+	token, err := source.Token(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "getting service token failed: %v", err)
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token), nil
+}
@@ -0,0 +1,151 @@
+// Package jwk parses JSON Web Keys (RFC 7517) and fetches and caches remote key sets, such as the JWKS
+// of an OpenID Connect provider.
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported key")
+	ErrInvalidKey     = errors.New("invalid key")
+)
+
+// Key is a JSON Web Key holding a public key. Only the members of RSA, EC and OKP (Ed25519) keys are supported.
+type Key struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Set is a JSON Web Key Set.
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// NewKey creates a signing key from an *rsa.PublicKey, an *ecdsa.PublicKey or an ed25519.PublicKey.
+func NewKey(kid string, alg string, publicKey crypto.PublicKey) (Key, error) {
+	k := Key{Kid: kid, Use: "sig", Alg: alg}
+
+	switch pub := publicKey.(type) {
+	case *rsa.PublicKey:
+		k.Kty = "RSA"
+		k.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		k.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		k.Kty = "EC"
+		k.Crv = pub.Curve.Params().Name
+		// coordinates are padded to the curve size as required by RFC 7518
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		k.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		k.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		k.Kty = "OKP"
+		k.Crv = "Ed25519"
+		k.X = base64.RawURLEncoding.EncodeToString(pub)
+	default:
+		return Key{}, fmt.Errorf("%w : %T", ErrUnsupportedKey, publicKey)
+	}
+
+	return k, nil
+}
+
+// PublicKey returns the key as an *rsa.PublicKey, an *ecdsa.PublicKey or an ed25519.PublicKey.
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		return k.rsaPublicKey()
+	case "EC":
+		return k.ecdsaPublicKey()
+	case "OKP":
+		return k.ed25519PublicKey()
+	default:
+		return nil, fmt.Errorf("%w : kty %q", ErrUnsupportedKey, k.Kty)
+	}
+}
+
+func (k Key) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeInt(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("%w : n : %v", ErrInvalidKey, err)
+	}
+
+	e, err := decodeInt(k.E)
+	if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 || e.Int64() < 3 {
+		return nil, fmt.Errorf("%w : e", ErrInvalidKey)
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k Key) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("%w : crv %q", ErrUnsupportedKey, k.Crv)
+	}
+
+	x, err := decodeInt(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("%w : x : %v", ErrInvalidKey, err)
+	}
+
+	y, err := decodeInt(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("%w : y : %v", ErrInvalidKey, err)
+	}
+
+	if !curve.IsOnCurve(x, y) {
+		return nil, fmt.Errorf("%w : point is not on curve %s", ErrInvalidKey, k.Crv)
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func (k Key) ed25519PublicKey() (ed25519.PublicKey, error) {
+	if k.Crv != "Ed25519" {
+		return nil, fmt.Errorf("%w : crv %q", ErrUnsupportedKey, k.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w : x", ErrInvalidKey)
+	}
+
+	return ed25519.PublicKey(x), nil
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
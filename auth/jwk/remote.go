@@ -0,0 +1,192 @@
+package jwk
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultCacheTTL           = time.Hour
+	defaultMinRefreshInterval = 30 * time.Second
+	defaultHTTPTimeout        = 30 * time.Second
+)
+
+// ErrKeyNotFound is returned when a key set has no signing key with the requested ID.
+var ErrKeyNotFound = errors.New("key not found")
+
+// RemoteSetOptions configures a RemoteSet.
+type RemoteSetOptions struct {
+	// CacheTTL is how long keys are cached when the response has no Cache-Control max-age. Defaults to an hour.
+	CacheTTL time.Duration
+	// MinRefreshInterval limits how often the set is refetched, whether for an unknown key ID or an expired
+	// cache. Defaults to 30 seconds.
+	MinRefreshInterval time.Duration
+	// HTTPClient defaults to a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+}
+
+func (o *RemoteSetOptions) withDefaults() RemoteSetOptions {
+	var res RemoteSetOptions
+	if o != nil {
+		res = *o
+	}
+	if res.CacheTTL <= 0 {
+		res.CacheTTL = defaultCacheTTL
+	}
+	if res.MinRefreshInterval <= 0 {
+		res.MinRefreshInterval = defaultMinRefreshInterval
+	}
+	if res.HTTPClient == nil {
+		res.HTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	return res
+}
+
+// RemoteSet is a key set fetched from a URL and cached. It is refetched when the cache expires or when
+// a key ID it does not know is requested, so that key rotations are picked up. Concurrent refetches are
+// merged into one, and cached keys keep being served while the endpoint is unavailable.
+type RemoteSet struct {
+	url   string
+	opts  RemoteSetOptions
+	group singleflight.Group
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	expiry    time.Time
+	lastFetch time.Time
+}
+
+// NewRemoteSet creates a RemoteSet for a JWKS URL. Keys are fetched on first use. Options can be nil.
+func NewRemoteSet(url string, options *RemoteSetOptions) *RemoteSet {
+	return &RemoteSet{
+		url:  url,
+		opts: options.withDefaults(),
+	}
+}
+
+// Key returns the signing key with the given ID. An empty kid matches the only key of a set holding a single key.
+func (s *RemoteSet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	now := time.Now()
+	key, found := s.lookup(kid)
+	fresh := now.Before(s.expiry)
+	recent := now.Sub(s.lastFetch) < s.opts.MinRefreshInterval
+	s.mu.Unlock()
+
+	if found && (fresh || recent) {
+		return key, nil
+	}
+	if !found && recent {
+		return nil, fmt.Errorf("%w : kid %q", ErrKeyNotFound, kid)
+	}
+
+	if _, err, _ := s.group.Do("", func() (interface{}, error) { return nil, s.refresh(ctx) }); err != nil {
+		// keep serving known keys while the endpoint is unavailable
+		if found {
+			return key, nil
+		}
+
+		return nil, fmt.Errorf("failed to fetch key set : %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, found = s.lookup(kid); !found {
+		return nil, fmt.Errorf("%w : kid %q", ErrKeyNotFound, kid)
+	}
+
+	return key, nil
+}
+
+// lookup must be called with s.mu held.
+func (s *RemoteSet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refresh fetches the set without holding s.mu, so that cached keys stay available meanwhile.
+func (s *RemoteSet) refresh(ctx context.Context) error {
+	fetched := time.Now()
+
+	s.mu.Lock()
+	s.lastFetch = fetched
+	s.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := s.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	var set Set
+	if err = json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// keys of unsupported types are skipped so that they do not hide the others
+		key, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys = keys
+	s.expiry = fetched.Add(cacheTTL(res.Header.Get("Cache-Control"), s.opts.CacheTTL))
+
+	return nil
+}
+
+// cacheTTL returns the max-age of a Cache-Control header, or def when there is none.
+func cacheTTL(cacheControl string, def time.Duration) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	return def
+}
@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/badfan/go-toolkit/auth/jwk"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const jwksMaxAge = 300
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrKeyMismatch          = errors.New("key does not match its algorithm")
+	ErrUnknownKey           = errors.New("unknown key id")
+	ErrNoSigningKey         = errors.New("key has no private part")
+	ErrDuplicateKey         = errors.New("duplicate key id")
+)
+
+// Key is a token signing key. A key with only a public part can verify tokens but not sign them,
+// e.g. the retired key of a rotation.
+type Key struct {
+	ID string
+	// Algorithm is one of HS256, RS256, ES256 and EdDSA.
+	Algorithm string
+	// Private is a []byte secret for HS256, an *rsa.PrivateKey, an *ecdsa.PrivateKey or an ed25519.PrivateKey.
+	Private interface{}
+	// Public is derived from Private when empty. It is unused for HS256.
+	Public crypto.PublicKey
+}
+
+// NewHMACKey creates an HS256 key from a shared secret. HMAC keys are never published in the JWKS.
+func NewHMACKey(id string, secret []byte) Key {
+	return Key{ID: id, Algorithm: jwt.SigningMethodHS256.Alg(), Private: secret}
+}
+
+// ParseKeyPEM creates a key from a PEM block holding a PKCS #8, PKCS #1 or SEC 1 private key, or a PKIX public key
+// for a verify-only key.
+func ParseKeyPEM(id string, algorithm string, pemBytes []byte) (Key, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return Key{}, fmt.Errorf("parsing key %s failed: no PEM block", id)
+	}
+
+	k := Key{ID: id, Algorithm: algorithm}
+
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		k.Private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		k.Private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		k.Private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		k.Public, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return Key{}, fmt.Errorf("parsing key %s failed: %w", id, err)
+	}
+
+	return k, nil
+}
+
+// signingMethod checks that the key parts match the algorithm, fills in the public part and returns its method.
+func (k *Key) signingMethod() (jwt.SigningMethod, error) {
+	method := jwt.GetSigningMethod(k.Algorithm)
+
+	var ok bool
+	switch method {
+	case jwt.SigningMethodHS256:
+		var secret []byte
+		secret, ok = k.Private.([]byte)
+		ok = ok && len(secret) > 0
+	case jwt.SigningMethodRS256:
+		if priv, isRSA := k.Private.(*rsa.PrivateKey); isRSA {
+			k.Public = &priv.PublicKey
+		}
+		_, ok = k.Public.(*rsa.PublicKey)
+	case jwt.SigningMethodES256:
+		if priv, isECDSA := k.Private.(*ecdsa.PrivateKey); isECDSA {
+			k.Public = &priv.PublicKey
+		}
+		var pub *ecdsa.PublicKey
+		pub, ok = k.Public.(*ecdsa.PublicKey)
+		ok = ok && pub.Curve == elliptic.P256()
+	case jwt.SigningMethodEdDSA:
+		if priv, isEd25519 := k.Private.(ed25519.PrivateKey); isEd25519 {
+			k.Public = priv.Public()
+		}
+		_, ok = k.Public.(ed25519.PublicKey)
+	default:
+		return nil, fmt.Errorf("%w : %q", ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%w : %s %s", ErrKeyMismatch, k.ID, k.Algorithm)
+	}
+
+	return method, nil
+}
+
+// same reports whether both keys hold the same key material.
+func (k *Key) same(other Key) bool {
+	if k.Algorithm != other.Algorithm {
+		return false
+	}
+	if k.Algorithm == jwt.SigningMethodHS256.Alg() {
+		secret, _ := k.Private.([]byte)
+		otherSecret, _ := other.Private.([]byte)
+
+		return hmac.Equal(secret, otherSecret)
+	}
+
+	pub, ok := k.Public.(interface{ Equal(crypto.PublicKey) bool })
+
+	return ok && pub.Equal(other.Public)
+}
+
+func (k *Key) verificationKey() interface{} {
+	if k.Algorithm == jwt.SigningMethodHS256.Alg() {
+		return k.Private
+	}
+
+	return k.Public
+}
+
+// KeySet holds the keys tokens are signed and verified with. Tokens are signed with the active key and carry
+// its ID in their kid header; every key of the set verifies the tokens it signed. Rotating keys is done by
+// activating a new key, and removing the previous one once the tokens it signed have expired.
+type KeySet struct {
+	mu     sync.RWMutex
+	active string
+	keys   map[string]Key
+}
+
+// NewKeySet creates a KeySet signing with active. Others are only used to verify tokens.
+func NewKeySet(active Key, others ...Key) (*KeySet, error) {
+	ks := &KeySet{keys: map[string]Key{}}
+
+	for _, k := range others {
+		if err := ks.Add(k); err != nil {
+			return nil, err
+		}
+	}
+	if err := ks.Rotate(active); err != nil {
+		return nil, err
+	}
+
+	return ks, nil
+}
+
+// Add adds a key that verifies tokens, without signing with it. Key IDs are unique; a key must be removed
+// before another one can take its ID.
+func (ks *KeySet) Add(k Key) error {
+	if _, err := k.signingMethod(); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if _, ok := ks.keys[k.ID]; ok {
+		return fmt.Errorf("%w : %s", ErrDuplicateKey, k.ID)
+	}
+	ks.keys[k.ID] = k
+
+	return nil
+}
+
+// Rotate adds a key and signs new tokens with it. The previously active key keeps verifying the tokens it signed.
+// A key added ahead of time is activated by rotating to the same key.
+func (ks *KeySet) Rotate(active Key) error {
+	if active.Private == nil {
+		return fmt.Errorf("%w : %s", ErrNoSigningKey, active.ID)
+	}
+	if _, err := active.signingMethod(); err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if k, ok := ks.keys[active.ID]; ok && !k.same(active) {
+		return fmt.Errorf("%w : %s", ErrDuplicateKey, active.ID)
+	}
+	ks.keys[active.ID] = active
+	ks.active = active.ID
+
+	return nil
+}
+
+// Remove removes a key, which invalidates the tokens it signed. The active key cannot be removed.
+func (ks *KeySet) Remove(id string) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if id != ks.active {
+		delete(ks.keys, id)
+	}
+}
+
+func (ks *KeySet) signingKey() Key {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	return ks.keys[ks.active]
+}
+
+func (ks *KeySet) key(id string) (Key, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	k, ok := ks.keys[id]
+	return k, ok
+}
+
+func (ks *KeySet) algorithms() []string {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	var algs []string
+	for _, k := range ks.keys {
+		if !contains(algs, k.Algorithm) {
+			algs = append(algs, k.Algorithm)
+		}
+	}
+
+	return algs
+}
+
+// JWKS returns the public keys of the set. HMAC keys are secret and left out.
+func (ks *KeySet) JWKS() (jwk.Set, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := jwk.Set{Keys: []jwk.Key{}}
+	for _, k := range ks.keys {
+		if strings.HasPrefix(k.Algorithm, "HS") {
+			continue
+		}
+
+		key, err := jwk.NewKey(k.ID, k.Algorithm, k.Public)
+		if err != nil {
+			return jwk.Set{}, err
+		}
+		set.Keys = append(set.Keys, key)
+	}
+
+	return set, nil
+}
+
+// JWKSHandler serves the public keys of the set, for services verifying our tokens. It is cached for
+// five minutes, so a new key should be added some time before it is activated.
+func (ks *KeySet) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		set, err := ks.JWKS()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAge))
+		_ = json.NewEncoder(w).Encode(set)
+	})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
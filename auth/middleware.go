@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/badfan/go-toolkit/tracer"
+	"github.com/gin-gonic/gin"
+)
+
+type claimsKey struct{}
+
+// scoped is implemented by *Claims[T] whatever T is.
+type scoped interface {
+	HasScopes(scopes ...string) bool
+}
+
+// MiddlewareOptions configures Middleware.
+type MiddlewareOptions struct {
+	// CookieName is the cookie the token is read from when there is no Authorization header. Empty disables cookies.
+	CookieName string
+	// Optional lets requests without a token through without claims. Requests with an invalid token are still rejected.
+	Optional bool
+	// Scopes must all be granted to the token.
+	Scopes []string
+}
+
+// Middleware authenticates requests with a bearer token from the Authorization header, or from a cookie,
+// validated with c. The claims are put into the request context, see ClaimsFromContext. Requests are rejected
+// with a 401 when the token is missing or invalid and with a 403 when it lacks a required scope.
+func Middleware[T any](c TokenConfig, options *MiddlewareOptions) gin.HandlerFunc {
+	return middleware(validator[T](c), options)
+}
+
+func middleware[T any](validate validateFunc[T], options *MiddlewareOptions) gin.HandlerFunc {
+	var opts MiddlewareOptions
+	if options != nil {
+		opts = *options
+	}
+
+	return func(ctx *gin.Context) {
+		token := bearerToken(ctx.Request, opts.CookieName)
+		if token == "" {
+			if opts.Optional {
+				ctx.Next()
+				return
+			}
+
+			abortMissingToken(ctx)
+			return
+		}
+
+		claims, err := validate(ctx.Request.Context(), token)
+		if errors.Is(err, ErrInvalidToken) {
+			tracer.AddSpanError(tracer.SpanFromContext(ctx.Request.Context()), err)
+			abortUnauthorized(ctx, "invalid token")
+			return
+		}
+		if err != nil {
+			tracer.AddSpanError(tracer.SpanFromContext(ctx.Request.Context()), err)
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+
+		if !claims.HasScopes(opts.Scopes...) {
+			abortForbidden(ctx, opts.Scopes)
+			return
+		}
+
+		ctx.Request = ctx.Request.WithContext(ContextWithClaims(ctx.Request.Context(), claims))
+		ctx.Next()
+	}
+}
+
+// RequireScopes rejects requests whose token lacks one of the scopes with a 403. It must run after Middleware,
+// e.g. on a route of a group authenticated by Middleware. Requests without claims are rejected with a 401.
+func RequireScopes(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		claims, ok := ctx.Request.Context().Value(claimsKey{}).(scoped)
+		if !ok {
+			abortMissingToken(ctx)
+			return
+		}
+
+		if !claims.HasScopes(scopes...) {
+			abortForbidden(ctx, scopes)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// ContextWithClaims returns a copy of ctx holding claims.
+func ContextWithClaims[T any](ctx context.Context, claims *Claims[T]) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the authenticated request. T must be the type Middleware was
+// instantiated with. Ctx can be a *gin.Context or the request context.
+func ClaimsFromContext[T any](ctx context.Context) (*Claims[T], bool) {
+	if ginCtx, ok := ctx.(*gin.Context); ok {
+		ctx = ginCtx.Request.Context()
+	}
+
+	claims, ok := ctx.Value(claimsKey{}).(*Claims[T])
+	return claims, ok
+}
+
+func bearerToken(r *http.Request, cookieName string) string {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+
+		return ""
+	}
+
+	if cookieName != "" {
+		if cookie, err := r.Cookie(cookieName); err == nil {
+			return cookie.Value
+		}
+	}
+
+	return ""
+}
+
+// abortMissingToken answers without an error code, as RFC 6750 requires when no credentials were sent.
+func abortMissingToken(ctx *gin.Context) {
+	ctx.Header("WWW-Authenticate", "Bearer")
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+}
+
+func abortUnauthorized(ctx *gin.Context, message string) {
+	ctx.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+func abortForbidden(ctx *gin.Context, scopes []string) {
+	ctx.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+	ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope, requires: " + strings.Join(scopes, " ")})
+}
@@ -0,0 +1,61 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+)
+
+// AuthCodeOption adds parameters to an authorization or a code exchange request.
+type AuthCodeOption func(url.Values)
+
+// WithNonce binds the ID token to the authorization request. The same nonce must be passed to VerifyIDToken.
+func WithNonce(nonce string) AuthCodeOption {
+	return func(v url.Values) {
+		v.Set("nonce", nonce)
+	}
+}
+
+// WithPKCE adds the S256 code challenge of a verifier to an authorization request.
+func WithPKCE(codeVerifier string) AuthCodeOption {
+	return func(v url.Values) {
+		v.Set("code_challenge", CodeChallenge(codeVerifier))
+		v.Set("code_challenge_method", "S256")
+	}
+}
+
+// WithCodeVerifier adds the PKCE code verifier to a code exchange request.
+func WithCodeVerifier(codeVerifier string) AuthCodeOption {
+	return func(v url.Values) {
+		v.Set("code_verifier", codeVerifier)
+	}
+}
+
+// WithParam adds any other parameter, e.g. "prompt" or "login_hint".
+func WithParam(key string, value string) AuthCodeOption {
+	return func(v url.Values) {
+		v.Set(key, value)
+	}
+}
+
+// NewCodeVerifier returns a random PKCE code verifier.
+func NewCodeVerifier() (string, error) {
+	return randomString(32)
+}
+
+// CodeChallenge returns the S256 code challenge of a PKCE code verifier.
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// randomString returns n random bytes encoded as base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
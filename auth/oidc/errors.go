@@ -0,0 +1,64 @@
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrProviderResponse is returned when a provider endpoint answers with an error.
+var ErrProviderResponse = errors.New("provider error response")
+
+// ResponseError is an error response of a provider endpoint, usually with a non-2xx status. Code and Description
+// are filled in when the body is an OAuth 2.0 error response, e.g. "invalid_grant", or a Google API error.
+type ResponseError struct {
+	URL         string
+	StatusCode  int
+	Body        string
+	Code        string
+	Description string
+}
+
+// NewResponseError creates the error of a response, parsing its body when it is an OAuth 2.0 or Google API error.
+func NewResponseError(url string, statusCode int, body []byte) *ResponseError {
+	e := &ResponseError{
+		URL:        url,
+		StatusCode: statusCode,
+		Body:       string(body),
+	}
+
+	// OAuth 2.0 errors are {"error": "invalid_grant", "error_description": "..."},
+	// Google API errors are {"error": {"code": 401, "message": "...", "status": "UNAUTHENTICATED"}}
+	var res struct {
+		Error            json.RawMessage `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if json.Unmarshal(body, &res) != nil || len(res.Error) == 0 {
+		return e
+	}
+
+	var apiErr struct {
+		Message string `json:"message"`
+		Status  string `json:"status"`
+	}
+	if json.Unmarshal(res.Error, &e.Code) == nil {
+		e.Description = res.ErrorDescription
+	} else if json.Unmarshal(res.Error, &apiErr) == nil {
+		e.Code = apiErr.Status
+		e.Description = apiErr.Message
+	}
+
+	return e
+}
+
+func (e *ResponseError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s : status %d : %s : %s : %s", ErrProviderResponse, e.StatusCode, e.URL, e.Code, e.Description)
+	}
+
+	return fmt.Sprintf("%s : status %d : %s : %s", ErrProviderResponse, e.StatusCode, e.URL, e.Body)
+}
+
+func (e *ResponseError) Unwrap() error {
+	return ErrProviderResponse
+}
@@ -0,0 +1,139 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	// ErrAuthorizationFailed is returned when the provider redirects back with an error, e.g. because the user
+	// denied access.
+	ErrAuthorizationFailed = errors.New("authorization failed")
+	// ErrMissingIDToken is returned when an OpenID Connect provider answers without an ID token.
+	ErrMissingIDToken = errors.New("token response has no id_token")
+)
+
+// CallbackResult is the outcome of a successful login.
+type CallbackResult struct {
+	Token *Token
+	// IDToken is nil for plain OAuth 2.0 providers, whose profile comes from the userinfo endpoint.
+	IDToken *IDToken
+	Profile *UserProfile
+	// RedirectTo is the local path passed when the login started.
+	RedirectTo string
+}
+
+// Flow runs the authorization code flow with state, nonce and PKCE against a provider.
+type Flow struct {
+	provider *Provider
+	store    StateStore
+}
+
+// NewFlow creates a Flow keeping its states in store.
+func NewFlow(provider *Provider, store StateStore) *Flow {
+	return &Flow{
+		provider: provider,
+		store:    store,
+	}
+}
+
+// AuthCodeURL starts a login and returns the provider URL to redirect the user to. RedirectTo is returned
+// by HandleCallback to send the user back where they were; it is dropped unless it is a local path.
+func (f *Flow) AuthCodeURL(w http.ResponseWriter, r *http.Request, redirectTo string) (string, error) {
+	state := &AuthState{CreatedAt: time.Now()}
+	if isLocalPath(redirectTo) {
+		state.RedirectTo = redirectTo
+	}
+
+	var err error
+	if state.State, err = randomString(16); err != nil {
+		return "", fmt.Errorf("failed to start login : %v", err)
+	}
+	if state.Nonce, err = randomString(16); err != nil {
+		return "", fmt.Errorf("failed to start login : %v", err)
+	}
+	if state.CodeVerifier, err = NewCodeVerifier(); err != nil {
+		return "", fmt.Errorf("failed to start login : %v", err)
+	}
+
+	if err = f.store.Save(w, r, state); err != nil {
+		return "", fmt.Errorf("failed to save login state : %v", err)
+	}
+
+	return f.provider.AuthCodeURL(state.State, WithNonce(state.Nonce), WithPKCE(state.CodeVerifier)), nil
+}
+
+// HandleCallback completes a login from the request the provider redirected the user with: it checks the state,
+// exchanges the code and verifies the ID token.
+func (f *Flow) HandleCallback(w http.ResponseWriter, r *http.Request) (*CallbackResult, error) {
+	query := r.URL.Query()
+	if code := query.Get("error"); code != "" {
+		return nil, fmt.Errorf("%w : %s : %s", ErrAuthorizationFailed, code, query.Get("error_description"))
+	}
+
+	if query.Get("state") == "" {
+		return nil, ErrInvalidState
+	}
+
+	state, err := f.store.Load(w, r, query.Get("state"))
+	if err != nil {
+		return nil, err
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("%w : no code in callback", ErrAuthorizationFailed)
+	}
+
+	ctx := r.Context()
+	token, err := f.provider.Exchange(ctx, code, WithCodeVerifier(state.CodeVerifier))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CallbackResult{
+		Token:      token,
+		RedirectTo: state.RedirectTo,
+	}
+
+	if token.IDToken == "" {
+		// only plain OAuth 2.0 providers may fall back to userinfo, which is not bound to the nonce
+		if f.provider.OpenID() {
+			return nil, ErrMissingIDToken
+		}
+
+		if res.Profile, err = f.provider.UserInfo(ctx, token.AccessToken); err != nil {
+			return nil, err
+		}
+
+		return res, nil
+	}
+
+	if res.IDToken, err = f.provider.VerifyIDToken(ctx, token.IDToken, state.Nonce); err != nil {
+		return nil, err
+	}
+	res.Profile = res.IDToken.Profile()
+
+	return res, nil
+}
+
+// isLocalPath reports whether a redirect target stays on the same origin, to prevent open redirects.
+// Browsers drop tabs and newlines from URLs, so "/\t/evil.com" would become "//evil.com": paths with control
+// characters are rejected.
+func isLocalPath(path string) bool {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return false
+	}
+	if strings.IndexFunc(path, unicode.IsControl) >= 0 {
+		return false
+	}
+
+	u, err := url.Parse(path)
+
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
@@ -0,0 +1,62 @@
+package oidc
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/badfan/go-toolkit/tracer"
+	"github.com/gin-gonic/gin"
+)
+
+// LoginHandler redirects to the provider login page. The optional "redirect_to" query parameter is
+// passed on to the callback.
+func (f *Flow) LoginHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		url, err := f.AuthCodeURL(c.Writer, c.Request, c.Query("redirect_to"))
+		if err != nil {
+			tracer.AddSpanError(tracer.SpanFromContext(c.Request.Context()), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+
+		c.Redirect(http.StatusFound, url)
+	}
+}
+
+// CallbackHandler completes logins and calls onLogin, which typically starts a session and redirects
+// to res.RedirectTo. Failed logins are answered with a generic JSON error; the cause is recorded on the span.
+func (f *Flow) CallbackHandler(onLogin func(c *gin.Context, res *CallbackResult)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := f.HandleCallback(c.Writer, c.Request)
+		if err != nil {
+			tracer.AddSpanError(tracer.SpanFromContext(c.Request.Context()), err)
+			status := callbackStatus(err)
+			c.AbortWithStatusJSON(status, gin.H{"error": callbackMessages[status]})
+			return
+		}
+
+		onLogin(c, res)
+	}
+}
+
+var callbackMessages = map[int]string{
+	http.StatusBadRequest:   "invalid login request",
+	http.StatusUnauthorized: "login failed",
+	http.StatusBadGateway:   "identity provider error",
+}
+
+func callbackStatus(err error) int {
+	var resErr *ResponseError
+
+	switch {
+	case errors.Is(err, ErrInvalidState), errors.Is(err, ErrAuthorizationFailed):
+		return http.StatusBadRequest
+	case errors.As(err, &resErr) && resErr.Code == "invalid_grant":
+		// the code was replayed or has expired
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidIDToken), errors.Is(err, ErrMissingIDToken):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusBadGateway
+	}
+}
@@ -0,0 +1,251 @@
+// Package oidc is a generic OpenID Connect client. Providers are configured through discovery
+// (".well-known/openid-configuration") or explicit endpoints, and users are returned as a normalized profile
+// whatever the provider.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/badfan/go-toolkit/auth/jwk"
+	"github.com/spf13/viper"
+)
+
+const (
+	discoveryPath      = "/.well-known/openid-configuration"
+	defaultHTTPTimeout = 30 * time.Second
+	maxErrorBodySize   = 64 << 10
+)
+
+// Well-known issuers and endpoints.
+const (
+	GoogleIssuer = "https://accounts.google.com"
+)
+
+// GitHubEndpoint is the endpoint of GitHub, which is a plain OAuth 2.0 provider without discovery.
+var GitHubEndpoint = Endpoint{
+	AuthURL:     "https://github.com/login/oauth/authorize",
+	TokenURL:    "https://github.com/login/oauth/access_token",
+	UserInfoURL: "https://api.github.com/user",
+}
+
+// MicrosoftIssuer returns the issuer of a Microsoft Entra ID tenant. Tenant is a tenant ID or domain.
+func MicrosoftIssuer(tenant string) string {
+	return "https://login.microsoftonline.com/" + tenant + "/v2.0"
+}
+
+var (
+	ErrIssuerMismatch  = errors.New("discovered issuer does not match the configured issuer")
+	ErrMissingEndpoint = errors.New("provider endpoint is not configured")
+)
+
+// AuthStyle is how the client authenticates to the token endpoint.
+type AuthStyle int
+
+const (
+	// AuthStyleAuto uses HTTP basic authentication when the provider advertises it, and form parameters otherwise.
+	AuthStyleAuto AuthStyle = iota
+	// AuthStyleBasic sends the client credentials with HTTP basic authentication (client_secret_basic).
+	AuthStyleBasic
+	// AuthStylePost sends the client credentials as form parameters (client_secret_post).
+	AuthStylePost
+)
+
+// Endpoint holds the URLs of a provider. Fields set in Config.Endpoint take precedence over the discovered ones.
+type Endpoint struct {
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	JWKSURL     string
+}
+
+// Config configures a Provider.
+type Config struct {
+	// Name identifies the provider in user profiles, e.g. "google".
+	Name string
+	// Issuer is the URL the discovery document is fetched from. It can be empty when Endpoint is complete.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes requested on login. Defaults to openid, email and profile. Providers requesting no openid scope,
+	// e.g. GitHub, are plain OAuth 2.0 providers whose users are read from the userinfo endpoint.
+	Scopes    []string
+	Endpoint  Endpoint
+	AuthStyle AuthStyle
+	// SigningAlgs are the accepted ID token signing algorithms. Defaults to the asymmetric ones the provider
+	// advertises, or RS256.
+	SigningAlgs []string
+	// ClockSkew is the tolerance applied to ID token time claims. Defaults to a minute.
+	ClockSkew time.Duration
+	// HTTPClient is used for every request to the provider. Defaults to a client with a 30 seconds timeout.
+	HTTPClient *http.Client
+}
+
+// ConfigFromViper reads the configuration of a provider from the viper keys under "oidc.<name>":
+// issuer, client_id, client_secret, redirect_url, scopes, auth_url, token_url, userinfo_url and jwks_url.
+func ConfigFromViper(name string) Config {
+	key := func(k string) string {
+		return "oidc." + name + "." + k
+	}
+
+	return Config{
+		Name:         name,
+		Issuer:       viper.GetString(key("issuer")),
+		ClientID:     viper.GetString(key("client_id")),
+		ClientSecret: viper.GetString(key("client_secret")),
+		RedirectURL:  viper.GetString(key("redirect_url")),
+		Scopes:       viper.GetStringSlice(key("scopes")),
+		Endpoint: Endpoint{
+			AuthURL:     viper.GetString(key("auth_url")),
+			TokenURL:    viper.GetString(key("token_url")),
+			UserInfoURL: viper.GetString(key("userinfo_url")),
+			JWKSURL:     viper.GetString(key("jwks_url")),
+		},
+	}
+}
+
+// Metadata is the part of a provider discovery document used by the client.
+type Metadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	EndSessionEndpoint                string   `json:"end_session_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// Provider is an OpenID Connect provider a client is registered with.
+type Provider struct {
+	config   Config
+	metadata Metadata
+	endpoint Endpoint
+	client   *http.Client
+	keySet   *jwk.RemoteSet
+}
+
+// NewProvider creates a Provider. When an issuer is configured, its discovery document is fetched and
+// its issuer must match the configured one.
+func NewProvider(ctx context.Context, c Config) (*Provider, error) {
+	p := &Provider{
+		config: c,
+		client: c.HTTPClient,
+	}
+	if p.client == nil {
+		p.client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	if len(p.config.Scopes) == 0 {
+		p.config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	if c.Issuer != "" {
+		if err := p.discover(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	p.endpoint = Endpoint{
+		AuthURL:     firstNonEmpty(c.Endpoint.AuthURL, p.metadata.AuthorizationEndpoint),
+		TokenURL:    firstNonEmpty(c.Endpoint.TokenURL, p.metadata.TokenEndpoint),
+		UserInfoURL: firstNonEmpty(c.Endpoint.UserInfoURL, p.metadata.UserInfoEndpoint),
+		JWKSURL:     firstNonEmpty(c.Endpoint.JWKSURL, p.metadata.JWKSURI),
+	}
+	if p.endpoint.TokenURL == "" {
+		return nil, fmt.Errorf("%w : token endpoint", ErrMissingEndpoint)
+	}
+	if p.endpoint.JWKSURL != "" {
+		p.keySet = jwk.NewRemoteSet(p.endpoint.JWKSURL, &jwk.RemoteSetOptions{HTTPClient: p.client})
+	}
+
+	return p, nil
+}
+
+// Name returns the configured name of the provider.
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// OpenID reports whether the provider is used as an OpenID Connect provider, i.e. whether the openid scope
+// is requested. Logins with such a provider must return an ID token.
+func (p *Provider) OpenID() bool {
+	return contains(p.config.Scopes, "openid")
+}
+
+// Metadata returns the discovery document of the provider. It is empty when no issuer is configured.
+func (p *Provider) Metadata() Metadata {
+	return p.metadata
+}
+
+// Endpoint returns the endpoints in use, discovered or configured.
+func (p *Provider) Endpoint() Endpoint {
+	return p.endpoint
+}
+
+func (p *Provider) discover(ctx context.Context) error {
+	issuer := strings.TrimSuffix(p.config.Issuer, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+discoveryPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to discover provider : %w", err)
+	}
+
+	if err = p.doJSON(req, &p.metadata); err != nil {
+		return fmt.Errorf("failed to discover provider : %w", err)
+	}
+
+	if strings.TrimSuffix(p.metadata.Issuer, "/") != issuer {
+		return fmt.Errorf("%w : got %q, want %q", ErrIssuerMismatch, p.metadata.Issuer, p.config.Issuer)
+	}
+
+	return nil
+}
+
+// doJSON sends a request and decodes a JSON response into v. Non-2xx responses are returned as a *ResponseError.
+func (p *Provider) doJSON(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBodySize))
+		return NewResponseError(req.URL.String(), res.StatusCode, body)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
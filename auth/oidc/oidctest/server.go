@@ -0,0 +1,298 @@
+// Package oidctest provides a local OpenID Connect provider built on httptest, to test code using package oidc
+// without a real provider.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/badfan/go-toolkit/auth/jwk"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "oidctest"
+
+type grant struct {
+	claims        map[string]interface{}
+	nonce         string
+	codeChallenge string
+}
+
+// Server is a mock OpenID Connect provider. Its URL is the issuer to configure oidc.Provider with.
+type Server struct {
+	*httptest.Server
+
+	ClientID     string
+	ClientSecret string
+	// User holds the claims of the user logging in through the authorization endpoint.
+	User map[string]interface{}
+
+	key           *rsa.PrivateKey
+	mu            sync.Mutex
+	codes         map[string]grant
+	tokens        map[string]map[string]interface{}
+	refreshTokens map[string]map[string]interface{}
+}
+
+// NewServer starts a Server accepting the given client credentials. It must be closed with Close.
+func NewServer(clientID string, clientSecret string) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		key:           key,
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		User:          map[string]interface{}{"sub": "user-1", "email": "user@example.com", "email_verified": true},
+		codes:         map[string]grant{},
+		tokens:        map[string]map[string]interface{}{},
+		refreshTokens: map[string]map[string]interface{}{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", s.handleDiscovery)
+	mux.HandleFunc("/authorize", s.handleAuthorize)
+	mux.HandleFunc("/token", s.handleToken)
+	mux.HandleFunc("/userinfo", s.handleUserInfo)
+	mux.HandleFunc("/jwks", s.handleJWKS)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// IssueCode returns an authorization code that exchanges for a token of a user with the given claims,
+// as if the user had logged in.
+func (s *Server) IssueCode(claims map[string]interface{}) string {
+	return s.issueCode(grant{claims: claims})
+}
+
+// IDToken signs an ID token with the given claims, added to a valid issuer, audience, issue time and a one hour
+// expiry. Claims override those defaults, e.g. to test an expired token.
+func (s *Server) IDToken(claims map[string]interface{}) string {
+	now := time.Now()
+	mapClaims := jwt.MapClaims{
+		"iss": s.URL,
+		"aud": s.ClientID,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range claims {
+		mapClaims[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, mapClaims)
+	token.Header["kid"] = keyID
+
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		panic(err)
+	}
+
+	return signed
+}
+
+func (s *Server) issueCode(g grant) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := randomString()
+	s.codes[code] = g
+
+	return code
+}
+
+func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/authorize",
+		"token_endpoint":                        s.URL + "/token",
+		"userinfo_endpoint":                     s.URL + "/userinfo",
+		"jwks_uri":                              s.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"response_types_supported":              []string{"code"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+// handleAuthorize logs User in without any prompt and redirects back with a code.
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != s.ClientID {
+		writeError(w, http.StatusBadRequest, "unauthorized_client", "unknown client")
+		return
+	}
+
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.String() == "" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "invalid redirect_uri")
+		return
+	}
+
+	values := redirect.Query()
+	if method := query.Get("code_challenge_method"); query.Get("code_challenge") != "" && method != "S256" {
+		writeError(w, http.StatusBadRequest, "invalid_request", "unsupported code_challenge_method "+method)
+		return
+	}
+
+	values.Set("code", s.issueCode(grant{
+		claims:        s.User,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}))
+	if state := query.Get("state"); state != "" {
+		values.Set("state", state)
+	}
+	redirect.RawQuery = values.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request", "expected a form POST")
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+	} else {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+	case "refresh_token":
+		s.handleRefresh(w, r)
+		return
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported_grant_type", r.PostForm.Get("grant_type"))
+		return
+	}
+
+	s.mu.Lock()
+	g, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_grant", "unknown or used code")
+		return
+	}
+
+	if g.codeChallenge != "" && g.codeChallenge != codeChallenge(r.PostForm.Get("code_verifier")) {
+		writeError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match code_challenge")
+		return
+	}
+
+	idClaims := map[string]interface{}{}
+	for k, v := range g.claims {
+		idClaims[k] = v
+	}
+	if g.nonce != "" {
+		idClaims["nonce"] = g.nonce
+	}
+
+	res := s.issueTokens(g.claims)
+	res["id_token"] = s.IDToken(idClaims)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// handleRefresh rotates refresh tokens: each one can only be used once.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	claims, ok := s.refreshTokens[r.PostForm.Get("refresh_token")]
+	delete(s.refreshTokens, r.PostForm.Get("refresh_token"))
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid_grant", "unknown or used refresh token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.issueTokens(claims))
+}
+
+func (s *Server) issueTokens(claims map[string]interface{}) map[string]interface{} {
+	accessToken, refreshToken := randomString(), randomString()
+
+	s.mu.Lock()
+	s.tokens[accessToken] = claims
+	s.refreshTokens[refreshToken] = claims
+	s.mu.Unlock()
+
+	return map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    3600,
+		"refresh_token": refreshToken,
+		"scope":         "openid email profile",
+	}
+}
+
+func (s *Server) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	accessToken := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	s.mu.Lock()
+	claims, ok := s.tokens[accessToken]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_token", "unknown access token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, claims)
+}
+
+func (s *Server) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	key, err := jwk.NewKey(keyID, "RS256", &s.key.PublicKey)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jwk.Set{Keys: []jwk.Key{key}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
+
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,104 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// UserProfile is a user as described by a provider, normalized across providers.
+type UserProfile struct {
+	// Provider is the configured name of the provider.
+	Provider          string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	GivenName         string
+	FamilyName        string
+	PreferredUsername string
+	Picture           string
+	Locale            string
+	// Claims holds every claim returned by the provider, including those not mapped above.
+	Claims map[string]interface{}
+}
+
+// UserInfo gets the profile of the user an access token was issued to from the userinfo endpoint.
+func (p *Provider) UserInfo(ctx context.Context, accessToken string) (*UserProfile, error) {
+	if p.endpoint.UserInfoURL == "" {
+		return nil, fmt.Errorf("failed to get user info : %w : userinfo endpoint", ErrMissingEndpoint)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint.UserInfoURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info : %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	var claims map[string]interface{}
+	if err = p.doJSON(req, &claims); err != nil {
+		return nil, fmt.Errorf("failed to get user info : %w", err)
+	}
+
+	profile := newUserProfile(p.config.Name, claims)
+	if profile.Subject == "" {
+		return nil, fmt.Errorf("failed to get user info : no subject in response")
+	}
+
+	return profile, nil
+}
+
+// newUserProfile maps standard OIDC claims to a profile, falling back on the names used by
+// non-OIDC providers such as GitHub and the legacy Google userinfo endpoint.
+func newUserProfile(provider string, claims map[string]interface{}) *UserProfile {
+	profile := &UserProfile{
+		Provider:          provider,
+		Subject:           claimString(claims, "sub", "id"),
+		Email:             claimString(claims, "email"),
+		EmailVerified:     claimBool(claims, "email_verified", "verified_email"),
+		Name:              claimString(claims, "name"),
+		GivenName:         claimString(claims, "given_name"),
+		FamilyName:        claimString(claims, "family_name"),
+		PreferredUsername: claimString(claims, "preferred_username", "login"),
+		Picture:           claimString(claims, "picture", "avatar_url"),
+		Locale:            claimString(claims, "locale"),
+		Claims:            claims,
+	}
+	if profile.Name == "" {
+		profile.Name = strings.TrimSpace(profile.GivenName + " " + profile.FamilyName)
+	}
+
+	return profile
+}
+
+func claimString(claims map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		switch v := claims[name].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+
+	return ""
+}
+
+func claimBool(claims map[string]interface{}, names ...string) bool {
+	for _, name := range names {
+		switch v := claims[name].(type) {
+		case bool:
+			return v
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const defaultRefreshLeeway = time.Minute
+
+// ErrReauthenticationRequired is returned when a token cannot be refreshed anymore, because there is no
+// refresh token or it was revoked or expired. The user has to log in again.
+var ErrReauthenticationRequired = errors.New("reauthentication required")
+
+// RefreshToken gets a new token with a refresh token. When the provider does not rotate refresh tokens,
+// the one passed is kept in the returned token.
+func (p *Provider) RefreshToken(ctx context.Context, refreshToken string) (*Token, error) {
+	if refreshToken == "" {
+		return nil, fmt.Errorf("failed to refresh token : %w", ErrReauthenticationRequired)
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "refresh_token")
+	values.Set("refresh_token", refreshToken)
+
+	token, err := p.requestToken(ctx, values)
+	if err != nil {
+		var resErr *ResponseError
+		if errors.As(err, &resErr) && resErr.Code == "invalid_grant" {
+			return nil, fmt.Errorf("failed to refresh token : %w : %v", ErrReauthenticationRequired, err)
+		}
+
+		return nil, fmt.Errorf("failed to refresh token : %w", err)
+	}
+
+	if token.RefreshToken == "" {
+		token.RefreshToken = refreshToken
+	}
+
+	return token, nil
+}
+
+// TokenSource returns the token of a user, refreshing it and saving the new one shortly before it expires.
+// It is safe for concurrent use; instances sharing a store may however refresh the same token concurrently,
+// which providers rotating refresh tokens can reject, so a user's token should be refreshed by one instance.
+type TokenSource struct {
+	provider *Provider
+	store    TokenStore
+	userID   string
+	leeway   time.Duration
+
+	mu    sync.Mutex
+	token *Token
+}
+
+// TokenSource creates a TokenSource for the token of a user kept in store. Tokens are refreshed when they
+// expire within leeway; zero means a minute.
+func (p *Provider) TokenSource(store TokenStore, userID string, leeway time.Duration) *TokenSource {
+	if leeway <= 0 {
+		leeway = defaultRefreshLeeway
+	}
+
+	return &TokenSource{
+		provider: p,
+		store:    store,
+		userID:   userID,
+		leeway:   leeway,
+	}
+}
+
+// Token returns a token that is valid for at least the leeway.
+func (s *TokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil || s.token.Expired(s.leeway) {
+		// another instance may have refreshed it already
+		token, err := s.store.Get(ctx, s.userID)
+		if err != nil {
+			return nil, err
+		}
+		s.token = token
+	}
+
+	if !s.token.Expired(s.leeway) {
+		return s.token, nil
+	}
+
+	token, err := s.provider.RefreshToken(ctx, s.token.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.store.Put(ctx, s.userID, token); err != nil {
+		return nil, fmt.Errorf("failed to save refreshed token : %v", err)
+	}
+	s.token = token
+
+	return token, nil
+}
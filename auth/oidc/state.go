@@ -0,0 +1,195 @@
+package oidc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStateTTL          = 10 * time.Minute
+	defaultStateCookiePrefix = "oidc_state_"
+)
+
+// ErrInvalidState is returned when a callback state is unknown, expired, tampered with or already used.
+var ErrInvalidState = errors.New("invalid or expired state")
+
+// AuthState is what a login must remember until its callback.
+type AuthState struct {
+	State        string    `json:"s"`
+	Nonce        string    `json:"n"`
+	CodeVerifier string    `json:"v"`
+	RedirectTo   string    `json:"r,omitempty"`
+	CreatedAt    time.Time `json:"t"`
+}
+
+// StateStore keeps AuthStates between a login and its callback. Load must return ErrInvalidState for an unknown
+// or expired state, and forget the state so that it cannot be used twice.
+type StateStore interface {
+	Save(w http.ResponseWriter, r *http.Request, state *AuthState) error
+	Load(w http.ResponseWriter, r *http.Request, state string) (*AuthState, error)
+}
+
+// MemoryStateStore keeps states in memory. It only works when callbacks reach the instance that served the login,
+// e.g. a single instance or sticky sessions.
+type MemoryStateStore struct {
+	ttl    time.Duration
+	mu     sync.Mutex
+	states map[string]*AuthState
+}
+
+// NewMemoryStateStore creates a MemoryStateStore. States older than ttl are rejected; zero means 10 minutes.
+func NewMemoryStateStore(ttl time.Duration) *MemoryStateStore {
+	if ttl <= 0 {
+		ttl = defaultStateTTL
+	}
+
+	return &MemoryStateStore{
+		ttl:    ttl,
+		states: map[string]*AuthState{},
+	}
+}
+
+func (m *MemoryStateStore) Save(_ http.ResponseWriter, _ *http.Request, state *AuthState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// abandoned logins are dropped here rather than by a background goroutine
+	for k, s := range m.states {
+		if time.Since(s.CreatedAt) > m.ttl {
+			delete(m.states, k)
+		}
+	}
+
+	m.states[state.State] = state
+
+	return nil
+}
+
+func (m *MemoryStateStore) Load(_ http.ResponseWriter, _ *http.Request, state string) (*AuthState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.states[state]
+	delete(m.states, state)
+	if !ok || time.Since(s.CreatedAt) > m.ttl {
+		return nil, ErrInvalidState
+	}
+
+	return s, nil
+}
+
+// CookieStateOptions configures a CookieStateStore.
+type CookieStateOptions struct {
+	// TTL defaults to 10 minutes.
+	TTL time.Duration
+	// Prefix of the cookie names, followed by the state. Defaults to "oidc_state_".
+	Prefix string
+	// Path defaults to "/". It must cover the callback route.
+	Path   string
+	Domain string
+	// Insecure allows the cookie over plain HTTP, for local development only.
+	Insecure bool
+}
+
+func (o *CookieStateOptions) withDefaults() CookieStateOptions {
+	var res CookieStateOptions
+	if o != nil {
+		res = *o
+	}
+	if res.TTL <= 0 {
+		res.TTL = defaultStateTTL
+	}
+	if res.Prefix == "" {
+		res.Prefix = defaultStateCookiePrefix
+	}
+	if res.Path == "" {
+		res.Path = "/"
+	}
+
+	return res
+}
+
+// CookieStateStore keeps states in HMAC-signed cookies on the user agent, so that any instance can handle
+// the callback. The nonce and code verifier are readable by the user agent, which is harmless since it is
+// the party they are issued to.
+type CookieStateStore struct {
+	secret []byte
+	opts   CookieStateOptions
+}
+
+// NewCookieStateStore creates a CookieStateStore signing cookies with secret, which should be at least 32 random
+// bytes shared by every instance. Options can be nil.
+func NewCookieStateStore(secret []byte, options *CookieStateOptions) *CookieStateStore {
+	return &CookieStateStore{
+		secret: secret,
+		opts:   options.withDefaults(),
+	}
+}
+
+func (c *CookieStateStore) Save(w http.ResponseWriter, _ *http.Request, state *AuthState) error {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	name := c.opts.Prefix + state.State
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	http.SetCookie(w, c.cookie(name, encoded+"."+c.sign(name, encoded), int(c.opts.TTL/time.Second)))
+
+	return nil
+}
+
+func (c *CookieStateStore) Load(w http.ResponseWriter, r *http.Request, state string) (*AuthState, error) {
+	name := c.opts.Prefix + state
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+	http.SetCookie(w, c.cookie(name, "", -1))
+
+	encoded, signature, ok := strings.Cut(cookie.Value, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(c.sign(name, encoded))) {
+		return nil, ErrInvalidState
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidState
+	}
+
+	var s AuthState
+	if err = json.Unmarshal(payload, &s); err != nil || s.State != state || time.Since(s.CreatedAt) > c.opts.TTL {
+		return nil, ErrInvalidState
+	}
+
+	return &s, nil
+}
+
+func (c *CookieStateStore) sign(name string, value string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(name + "=" + value))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *CookieStateStore) cookie(name string, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     c.opts.Path,
+		Domain:   c.opts.Domain,
+		MaxAge:   maxAge,
+		Secure:   !c.opts.Insecure,
+		HttpOnly: true,
+		// the callback is a top-level cross-site navigation, which Lax cookies are sent with
+		SameSite: http.SameSiteLaxMode,
+	}
+}
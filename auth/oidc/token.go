@@ -0,0 +1,176 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Token is the response of the token endpoint.
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type,omitempty"`
+	// RefreshToken is only issued when offline access was requested, e.g. with the "offline_access" scope,
+	// or with WithParam("access_type", "offline") and WithParam("prompt", "consent") for Google.
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// IDToken is the raw ID token. It is empty for plain OAuth 2.0 providers.
+	IDToken string `json:"id_token,omitempty"`
+	// Scope is the granted scope, which may differ from the requested one. It is empty when the provider does not say.
+	Scope string `json:"scope,omitempty"`
+	// Expiry is when the access token expires. It is zero when the provider does not say.
+	Expiry time.Time `json:"expiry"`
+	// Extra holds the provider-specific members of the token response.
+	Extra map[string]interface{} `json:"extra,omitempty"`
+}
+
+// Expired reports whether the access token expires within leeway. Tokens without expiry never expire.
+func (t *Token) Expired(leeway time.Duration) bool {
+	return !t.Expiry.IsZero() && time.Now().Add(leeway).After(t.Expiry)
+}
+
+type tokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	RefreshToken string    `json:"refresh_token"`
+	IDToken      string    `json:"id_token"`
+	Scope        string    `json:"scope"`
+	ExpiresIn    expiresIn `json:"expires_in"`
+
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// expiresIn accepts both numbers and strings, which some providers send instead.
+type expiresIn int64
+
+func (e *expiresIn) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid expires_in %s", b)
+	}
+	*e = expiresIn(v)
+
+	return nil
+}
+
+// AuthCodeURL returns the URL of the provider login page. The user is redirected back to the configured
+// redirect URL with a code to pass to Exchange and the given state, which must be checked against the one sent.
+// Flow does all of this, including nonce and PKCE, and should be preferred.
+func (p *Provider) AuthCodeURL(state string, opts ...AuthCodeOption) string {
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.config.ClientID)
+	values.Set("redirect_uri", p.config.RedirectURL)
+	values.Set("scope", strings.Join(p.config.Scopes, " "))
+	values.Set("state", state)
+	for _, opt := range opts {
+		opt(values)
+	}
+
+	if strings.Contains(p.endpoint.AuthURL, "?") {
+		return p.endpoint.AuthURL + "&" + values.Encode()
+	}
+
+	return p.endpoint.AuthURL + "?" + values.Encode()
+}
+
+// Exchange exchanges an authorization code for a token. WithCodeVerifier must be passed when the
+// authorization request used PKCE.
+func (p *Provider) Exchange(ctx context.Context, code string, opts ...AuthCodeOption) (*Token, error) {
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", p.config.RedirectURL)
+	for _, opt := range opts {
+		opt(values)
+	}
+
+	token, err := p.requestToken(ctx, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code : %w", err)
+	}
+
+	return token, nil
+}
+
+func (p *Provider) requestToken(ctx context.Context, values url.Values) (*Token, error) {
+	basic := p.useBasicAuth()
+	if !basic {
+		values.Set("client_id", p.config.ClientID)
+		if p.config.ClientSecret != "" {
+			values.Set("client_secret", p.config.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint.TokenURL, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basic {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var raw json.RawMessage
+	if err = p.doJSON(req, &raw); err != nil {
+		return nil, err
+	}
+
+	var res tokenResponse
+	if err = json.Unmarshal(raw, &res); err != nil {
+		return nil, err
+	}
+
+	// GitHub answers errors with a 200 status
+	if res.Error != "" {
+		return nil, &ResponseError{
+			URL:         p.endpoint.TokenURL,
+			StatusCode:  http.StatusOK,
+			Code:        res.Error,
+			Description: res.ErrorDescription,
+		}
+	}
+	if res.AccessToken == "" {
+		return nil, fmt.Errorf("no access token in response")
+	}
+
+	token := &Token{
+		AccessToken:  res.AccessToken,
+		TokenType:    res.TokenType,
+		RefreshToken: res.RefreshToken,
+		IDToken:      res.IDToken,
+		Scope:        res.Scope,
+	}
+	if err = json.Unmarshal(raw, &token.Extra); err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"access_token", "token_type", "refresh_token", "id_token", "scope", "expires_in"} {
+		delete(token.Extra, k)
+	}
+	if res.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(res.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+func (p *Provider) useBasicAuth() bool {
+	switch p.config.AuthStyle {
+	case AuthStyleBasic:
+		return true
+	case AuthStylePost:
+		return false
+	default:
+		return contains(p.metadata.TokenEndpointAuthMethodsSupported, "client_secret_basic")
+	}
+}
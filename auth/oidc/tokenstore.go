@@ -0,0 +1,217 @@
+package oidc
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTokenNotFound        = errors.New("token not found")
+	ErrInvalidEncryptionKey = errors.New("encryption keys must be 32 bytes long")
+)
+
+// TokenStore keeps the tokens of users. Get must return ErrTokenNotFound when a user has no token.
+type TokenStore interface {
+	Get(ctx context.Context, userID string) (*Token, error)
+	Put(ctx context.Context, userID string, token *Token) error
+	Delete(ctx context.Context, userID string) error
+}
+
+// MemoryTokenStore keeps tokens in memory, unencrypted. It is meant for tests and single instance tools.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: map[string]Token{}}
+}
+
+func (m *MemoryTokenStore) Get(_ context.Context, userID string) (*Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.tokens[userID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+
+	return &token, nil
+}
+
+func (m *MemoryTokenStore) Put(_ context.Context, userID string, token *Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tokens[userID] = *token
+
+	return nil
+}
+
+func (m *MemoryTokenStore) Delete(_ context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.tokens, userID)
+
+	return nil
+}
+
+// StoredToken is the encrypted token of a user for a provider.
+type StoredToken struct {
+	Provider   string `gorm:"primaryKey;size:64"`
+	UserID     string `gorm:"primaryKey"`
+	Ciphertext []byte `gorm:"not null"`
+	UpdatedAt  time.Time
+}
+
+func (StoredToken) TableName() string {
+	return "oidc_tokens"
+}
+
+type GormTokenStoreConfig struct {
+	// Provider separates the tokens of different providers in the same table.
+	Provider string
+	// EncryptionKeys are 32 bytes AES-256 keys. Tokens are encrypted with the first one and decrypted with
+	// any of them, so keys can be rotated by prepending a new one.
+	EncryptionKeys [][]byte
+}
+
+// GormTokenStore keeps tokens in Postgres, encrypted with AES-256-GCM.
+type GormTokenStore struct {
+	db       *gorm.DB
+	provider string
+	aeads    []cipher.AEAD
+}
+
+func NewGormTokenStore(db *gorm.DB, c GormTokenStoreConfig) (*GormTokenStore, error) {
+	if len(c.EncryptionKeys) == 0 {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	s := &GormTokenStore{
+		db:       db,
+		provider: c.Provider,
+	}
+	for _, key := range c.EncryptionKeys {
+		if len(key) != 32 {
+			return nil, ErrInvalidEncryptionKey
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		s.aeads = append(s.aeads, aead)
+	}
+
+	return s, nil
+}
+
+// Migrate creates or updates the table holding tokens.
+func (s *GormTokenStore) Migrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(&StoredToken{})
+}
+
+func (s *GormTokenStore) Get(ctx context.Context, userID string) (*Token, error) {
+	var stored StoredToken
+	err := s.db.WithContext(ctx).Where("provider = ? AND user_id = ?", s.provider, userID).Take(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token : %v", err)
+	}
+
+	plaintext, err := s.decrypt(stored.Ciphertext, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token : %v", err)
+	}
+
+	var token Token
+	if err = json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("failed to decode token : %v", err)
+	}
+
+	return &token, nil
+}
+
+func (s *GormTokenStore) Put(ctx context.Context, userID string, token *Token) error {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encode token : %v", err)
+	}
+
+	ciphertext, err := s.encrypt(plaintext, userID)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt token : %v", err)
+	}
+
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ciphertext", "updated_at"}),
+	}).Create(&StoredToken{Provider: s.provider, UserID: userID, Ciphertext: ciphertext}).Error
+	if err != nil {
+		return fmt.Errorf("failed to put token : %v", err)
+	}
+
+	return nil
+}
+
+func (s *GormTokenStore) Delete(ctx context.Context, userID string) error {
+	err := s.db.WithContext(ctx).Where("provider = ? AND user_id = ?", s.provider, userID).Delete(&StoredToken{}).Error
+	if err != nil {
+		return fmt.Errorf("failed to delete token : %v", err)
+	}
+
+	return nil
+}
+
+// encrypt seals plaintext behind a random nonce. The provider and user are authenticated as additional data,
+// so that a ciphertext copied to another row does not decrypt.
+func (s *GormTokenStore) encrypt(plaintext []byte, userID string) ([]byte, error) {
+	aead := s.aeads[0]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, s.additionalData(userID)), nil
+}
+
+func (s *GormTokenStore) decrypt(ciphertext []byte, userID string) ([]byte, error) {
+	for _, aead := range s.aeads {
+		if len(ciphertext) < aead.NonceSize() {
+			break
+		}
+
+		nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, sealed, s.additionalData(userID)); err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return nil, errors.New("no encryption key matches")
+}
+
+// additionalData binds a ciphertext to its row. The provider is length-prefixed, so that no other pair of
+// provider and user ID gives the same bytes.
+func (s *GormTokenStore) additionalData(userID string) []byte {
+	return []byte(fmt.Sprintf("%d:%s%s", len(s.provider), s.provider, userID))
+}
@@ -0,0 +1,156 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const defaultClockSkew = time.Minute
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrNonceMismatch  = errors.New("id token nonce mismatch")
+)
+
+// IDToken holds the verified claims of an ID token.
+type IDToken struct {
+	Issuer          string
+	Subject         string
+	Audience        []string
+	Expiry          time.Time
+	IssuedAt        time.Time
+	AuthTime        time.Time
+	Nonce           string
+	AuthorizedParty string
+	// Claims holds every claim of the token.
+	Claims map[string]interface{}
+
+	provider string
+}
+
+// Profile returns the user described by the token.
+func (t *IDToken) Profile() *UserProfile {
+	return newUserProfile(t.provider, t.Claims)
+}
+
+// Decode decodes the claims of the token into v, which is typically a struct with json tags
+// for provider-specific claims.
+func (t *IDToken) Decode(v interface{}) error {
+	b, err := json.Marshal(t.Claims)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string           `json:"nonce"`
+	AuthorizedParty string           `json:"azp"`
+	AuthTime        *jwt.NumericDate `json:"auth_time"`
+}
+
+// VerifyIDToken verifies the signature of an ID token against the provider JWKS, and its issuer, audience,
+// expiry and nonce, allowing for the configured clock skew. Nonce is the one sent in the authorization
+// request; an empty nonce skips the check and must only be used when none was sent.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*IDToken, error) {
+	if p.keySet == nil {
+		return nil, fmt.Errorf("%w : %v : jwks endpoint", ErrInvalidIDToken, ErrMissingEndpoint)
+	}
+
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(p.signingAlgs()),
+		jwt.WithLeeway(p.clockSkew()),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
+	var claims idTokenClaims
+	_, err := parser.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.keySet.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w : %w", ErrInvalidIDToken, err)
+	}
+
+	if !p.validIssuer(claims.Issuer) {
+		return nil, fmt.Errorf("%w : %w : %q", ErrInvalidIDToken, jwt.ErrTokenInvalidIssuer, claims.Issuer)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w : azp %q is not the client", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	if nonce != "" && claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w : %w", ErrInvalidIDToken, ErrNonceMismatch)
+	}
+
+	// the signature is valid, so the payload can be decoded again without checks for the raw claims
+	var raw jwt.MapClaims
+	if _, _, err = jwt.NewParser().ParseUnverified(rawIDToken, &raw); err != nil {
+		return nil, fmt.Errorf("%w : %w", ErrInvalidIDToken, err)
+	}
+
+	token := &IDToken{
+		Issuer:          claims.Issuer,
+		Subject:         claims.Subject,
+		Audience:        claims.Audience,
+		Expiry:          claims.ExpiresAt.Time,
+		Nonce:           claims.Nonce,
+		AuthorizedParty: claims.AuthorizedParty,
+		Claims:          raw,
+		provider:        p.config.Name,
+	}
+	if claims.IssuedAt != nil {
+		token.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.AuthTime != nil {
+		token.AuthTime = claims.AuthTime.Time
+	}
+
+	return token, nil
+}
+
+func (p *Provider) validIssuer(issuer string) bool {
+	expected := strings.TrimSuffix(firstNonEmpty(p.metadata.Issuer, p.config.Issuer), "/")
+	if strings.TrimSuffix(issuer, "/") == expected {
+		return true
+	}
+
+	// Google ID tokens may carry the issuer without its scheme
+	return expected == GoogleIssuer && issuer == strings.TrimPrefix(GoogleIssuer, "https://")
+}
+
+func (p *Provider) signingAlgs() []string {
+	if len(p.config.SigningAlgs) > 0 {
+		return p.config.SigningAlgs
+	}
+
+	var algs []string
+	for _, alg := range p.metadata.IDTokenSigningAlgValuesSupported {
+		// symmetric and unsigned tokens cannot be verified against a JWKS
+		if alg != "none" && !strings.HasPrefix(alg, "HS") {
+			algs = append(algs, alg)
+		}
+	}
+	if len(algs) == 0 {
+		return []string{"RS256"}
+	}
+
+	return algs
+}
+
+func (p *Provider) clockSkew() time.Duration {
+	if p.config.ClockSkew > 0 {
+		return p.config.ClockSkew
+	}
+
+	return defaultClockSkew
+}
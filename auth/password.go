@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	PasswordArgon2id = "argon2id"
+	PasswordBcrypt   = "bcrypt"
+
+	// bcryptMaxBytes is the longest password bcrypt hashes.
+	bcryptMaxBytes = 72
+)
+
+var (
+	ErrUnknownPasswordHash = errors.New("unknown password hash format")
+	ErrWeakPassword        = errors.New("password does not meet the policy")
+)
+
+// Argon2Params are the Argon2id cost parameters. The defaults follow the OWASP recommendation.
+type Argon2Params struct {
+	// Time is the number of passes. Defaults to 3.
+	Time uint32
+	// Memory in KiB. Defaults to 64 MiB.
+	Memory uint32
+	// Threads defaults to 2.
+	Threads uint8
+	// KeyLength defaults to 32 bytes.
+	KeyLength uint32
+	// SaltLength defaults to 16 bytes.
+	SaltLength uint32
+}
+
+// PasswordHasherOptions configures a PasswordHasher.
+type PasswordHasherOptions struct {
+	// Algorithm new hashes are made with, PasswordArgon2id or PasswordBcrypt. Defaults to PasswordArgon2id.
+	Algorithm string
+	Argon2    Argon2Params
+	// BcryptCost defaults to 12.
+	BcryptCost int
+}
+
+func (o *PasswordHasherOptions) withDefaults() PasswordHasherOptions {
+	var res PasswordHasherOptions
+	if o != nil {
+		res = *o
+	}
+	if res.Algorithm == "" {
+		res.Algorithm = PasswordArgon2id
+	}
+	if res.Argon2.Time == 0 {
+		res.Argon2.Time = 3
+	}
+	if res.Argon2.Memory == 0 {
+		res.Argon2.Memory = 64 * 1024
+	}
+	if res.Argon2.Threads == 0 {
+		res.Argon2.Threads = 2
+	}
+	if res.Argon2.KeyLength == 0 {
+		res.Argon2.KeyLength = 32
+	}
+	if res.Argon2.SaltLength == 0 {
+		res.Argon2.SaltLength = 16
+	}
+	if res.BcryptCost == 0 {
+		res.BcryptCost = 12
+	}
+
+	return res
+}
+
+// PasswordHasher hashes passwords and verifies them against hashes made with any supported algorithm and
+// parameters, so that both can be changed without invalidating existing passwords.
+type PasswordHasher struct {
+	opts PasswordHasherOptions
+}
+
+func NewPasswordHasher(options *PasswordHasherOptions) (*PasswordHasher, error) {
+	opts := options.withDefaults()
+	if opts.Algorithm != PasswordArgon2id && opts.Algorithm != PasswordBcrypt {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, opts.Algorithm)
+	}
+
+	return &PasswordHasher{opts: opts}, nil
+}
+
+// Hash returns the encoded hash of a password, in the PHC format for Argon2id
+// ("$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>") and the modular crypt format for bcrypt.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.opts.Algorithm == PasswordBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("hashing password failed: %w", err)
+		}
+
+		return string(hash), nil
+	}
+
+	p := h.opts.Argon2
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("hashing password failed: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// MaxPasswordBytes is the longest password in bytes Hash accepts, or 0 when it is unbounded.
+func (h *PasswordHasher) MaxPasswordBytes() int {
+	if h.opts.Algorithm == PasswordBcrypt {
+		return bcryptMaxBytes
+	}
+
+	return 0
+}
+
+// Verify reports whether a password matches an encoded hash, and whether the hash should be replaced because
+// it was made with another algorithm or weaker parameters than the current ones.
+func (h *PasswordHasher) Verify(encoded string, password string) (bool, bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+
+		other := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(key, other) != 1 {
+			return false, false, nil
+		}
+
+		current := h.opts.Argon2
+		rehash := h.opts.Algorithm != PasswordArgon2id ||
+			p.Time < current.Time || p.Memory < current.Memory || p.Threads < current.Threads ||
+			uint32(len(key)) < current.KeyLength || uint32(len(salt)) < current.SaltLength
+
+		return true, rehash, nil
+	case strings.HasPrefix(encoded, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("%w: %v", ErrUnknownPasswordHash, err)
+		}
+
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return false, false, fmt.Errorf("%w: %v", ErrUnknownPasswordHash, err)
+		}
+
+		return true, h.opts.Algorithm != PasswordBcrypt || cost < h.opts.BcryptCost, nil
+	default:
+		return false, false, ErrUnknownPasswordHash
+	}
+}
+
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+
+	// argon2.IDKey panics on zero parameters, so a corrupted hash must not reach it
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil ||
+		p.Memory == 0 || p.Time == 0 || p.Threads == 0 {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, ErrUnknownPasswordHash
+	}
+
+	return p, salt, key, nil
+}
+
+// PasswordPolicy are the rules new passwords must follow. Length is counted in characters.
+type PasswordPolicy struct {
+	// MinLength defaults to 12.
+	MinLength int
+	// MaxLength bounds the cost of hashing. Defaults to 128.
+	MaxLength int
+	// MaxBytes bounds the UTF-8 length of passwords, e.g. to the 72 bytes bcrypt hashes. Unbounded when zero.
+	MaxBytes      int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Forbidden lists passwords that are rejected whatever their case, e.g. the most common ones.
+	Forbidden []string
+}
+
+// PolicyError lists the rules of a PasswordPolicy a password breaks.
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return ErrWeakPassword.Error() + ": " + strings.Join(e.Violations, ", ")
+}
+
+func (e *PolicyError) Unwrap() error {
+	return ErrWeakPassword
+}
+
+// Check returns a *PolicyError if a password breaks the policy. The identifiers of the account, e.g. its username
+// and email, must not be part of the password.
+func (p *PasswordPolicy) Check(password string, identifiers ...string) error {
+	minLength, maxLength := p.MinLength, p.MaxLength
+	if minLength <= 0 {
+		minLength = 12
+	}
+	if maxLength <= 0 {
+		maxLength = 128
+	}
+
+	var violations []string
+	length := utf8.RuneCountInString(password)
+	if length < minLength {
+		violations = append(violations, fmt.Sprintf("shorter than %d characters", minLength))
+	}
+	if length > maxLength {
+		violations = append(violations, fmt.Sprintf("longer than %d characters", maxLength))
+	}
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		violations = append(violations, fmt.Sprintf("longer than %d bytes", p.MaxBytes))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violations = append(violations, "no uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, "no lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "no digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "no symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, forbidden := range p.Forbidden {
+		if lowered == strings.ToLower(forbidden) {
+			violations = append(violations, "too common")
+			break
+		}
+	}
+	for _, identifier := range identifiers {
+		if len(identifier) >= 3 && strings.Contains(lowered, strings.ToLower(identifier)) {
+			violations = append(violations, "contains account details")
+			break
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultRevocationCacheTTL = 30 * time.Second
+
+// RevocationList records the IDs (jti) of revoked tokens until the tokens expire.
+type RevocationList interface {
+	Revoke(ctx context.Context, id string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryRevocationList keeps revoked token IDs in memory. It only fits a single instance.
+type MemoryRevocationList struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+func NewMemoryRevocationList() *MemoryRevocationList {
+	return &MemoryRevocationList{revoked: map[string]time.Time{}}
+}
+
+func (m *MemoryRevocationList) Revoke(_ context.Context, id string, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// entries are dropped once the token would be rejected as expired anyway
+	now := time.Now()
+	for k, exp := range m.revoked {
+		if now.After(exp) {
+			delete(m.revoked, k)
+		}
+	}
+
+	m.revoked[id] = expiresAt
+
+	return nil
+}
+
+func (m *MemoryRevocationList) IsRevoked(_ context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.revoked[id]
+	return ok, nil
+}
+
+// RevokedToken is the ID of a revoked token, kept until the token expires.
+type RevokedToken struct {
+	ID        string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (RevokedToken) TableName() string {
+	return "auth_revoked_tokens"
+}
+
+// GormRevocationList keeps revoked token IDs in Postgres.
+type GormRevocationList struct {
+	db *gorm.DB
+}
+
+func NewGormRevocationList(db *gorm.DB) *GormRevocationList {
+	return &GormRevocationList{db: db}
+}
+
+// Migrate creates or updates the table holding revoked token IDs.
+func (g *GormRevocationList) Migrate(ctx context.Context) error {
+	return g.db.WithContext(ctx).AutoMigrate(&RevokedToken{})
+}
+
+func (g *GormRevocationList) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	err := g.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&RevokedToken{ID: id, ExpiresAt: expiresAt}).Error
+	if err != nil {
+		return fmt.Errorf("revoking token failed: %w", err)
+	}
+
+	return nil
+}
+
+func (g *GormRevocationList) IsRevoked(ctx context.Context, id string) (bool, error) {
+	var count int64
+	if err := g.db.WithContext(ctx).Model(&RevokedToken{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Prune deletes the IDs of tokens that have expired and returns how many were deleted.
+// It is meant to be run periodically, e.g. from a scheduled job.
+func (g *GormRevocationList) Prune(ctx context.Context) (int64, error) {
+	res := g.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&RevokedToken{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("pruning revoked tokens failed: %w", res.Error)
+	}
+
+	return res.RowsAffected, nil
+}
+
+// CachedRevocationList caches the lookups of another list for a TTL, which is therefore how long a revocation
+// made by another instance can take to be enforced. Revocations made through the cache are cached until
+// the token expires.
+type CachedRevocationList struct {
+	list RevocationList
+	ttl  time.Duration
+
+	mu        sync.Mutex
+	revoked   map[string]bool
+	expiry    map[string]time.Time
+	lastPrune time.Time
+}
+
+// NewCachedRevocationList wraps list with a local cache. A zero ttl means 30 seconds.
+func NewCachedRevocationList(list RevocationList, ttl time.Duration) *CachedRevocationList {
+	if ttl <= 0 {
+		ttl = defaultRevocationCacheTTL
+	}
+
+	return &CachedRevocationList{
+		list:    list,
+		ttl:     ttl,
+		revoked: map[string]bool{},
+		expiry:  map[string]time.Time{},
+	}
+}
+
+func (c *CachedRevocationList) Revoke(ctx context.Context, id string, expiresAt time.Time) error {
+	if err := c.list.Revoke(ctx, id, expiresAt); err != nil {
+		return err
+	}
+
+	c.store(id, true, expiresAt)
+
+	return nil
+}
+
+func (c *CachedRevocationList) IsRevoked(ctx context.Context, id string) (bool, error) {
+	c.mu.Lock()
+	revoked, ok := c.revoked[id]
+	if ok && time.Now().After(c.expiry[id]) {
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		return revoked, nil
+	}
+
+	revoked, err := c.list.IsRevoked(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	c.store(id, revoked, time.Now().Add(c.ttl))
+
+	return revoked, nil
+}
+
+func (c *CachedRevocationList) store(id string, revoked bool, expiry time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.lastPrune) > c.ttl {
+		for k, exp := range c.expiry {
+			if now.After(exp) {
+				delete(c.expiry, k)
+				delete(c.revoked, k)
+			}
+		}
+		c.lastPrune = now
+	}
+
+	c.revoked[id] = revoked
+	c.expiry[id] = expiry
+}
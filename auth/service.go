@@ -0,0 +1,344 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+)
+
+const (
+	defaultServiceTokenTTL = 5 * time.Minute
+	defaultServiceMaxTTL   = 10 * time.Minute
+	defaultServiceTrustKey = "services.trusted"
+)
+
+var ErrUntrustedService = errors.New("untrusted service")
+
+// ServiceClaims are the custom claims of service tokens. The calling service is the subject of the token,
+// and its requested scopes are in Claims.Scopes.
+type ServiceClaims struct{}
+
+// serviceTokenClaims are the claims service tokens are minted with.
+type serviceTokenClaims struct {
+	Scope string `json:"scope,omitempty"`
+}
+
+// ServiceTokenOptions configures a ServiceTokenSource.
+type ServiceTokenOptions struct {
+	// TTL of the minted tokens. Defaults to 5 minutes, and must not exceed the MaxTTL of the called service.
+	TTL time.Duration
+	// RefreshBefore is how long before expiry a token is replaced. Defaults to a fifth of TTL.
+	RefreshBefore time.Duration
+	// Scopes requested in every token. The called service rejects scopes its trust list does not grant.
+	Scopes []string
+}
+
+func (o *ServiceTokenOptions) withDefaults() ServiceTokenOptions {
+	var res ServiceTokenOptions
+	if o != nil {
+		res = *o
+	}
+	if res.TTL <= 0 {
+		res.TTL = defaultServiceTokenTTL
+	}
+	if res.RefreshBefore <= 0 || res.RefreshBefore >= res.TTL {
+		res.RefreshBefore = res.TTL / 5
+	}
+
+	return res
+}
+
+// ServiceTokenSource mints the tokens a service calls another one with. They are signed with the active key
+// of the calling service, issued by and for the calling service and intended for the called one only.
+// A token is reused until it is about to expire.
+//
+// It is a TokenSource for UnaryClientInterceptor and TokenTransport, and gRPC per-RPC credentials
+// for grpc.WithPerRPCCredentials.
+type ServiceTokenSource struct {
+	config TokenConfig
+	name   string
+	opts   ServiceTokenOptions
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// NewServiceTokenSource creates a ServiceTokenSource for the service name, calling the service audience.
+func NewServiceTokenSource(name string, keys *KeySet, audience string, options *ServiceTokenOptions) *ServiceTokenSource {
+	return &ServiceTokenSource{
+		config: TokenConfig{
+			Keys:     keys,
+			Issuer:   name,
+			Audience: []string{audience},
+		},
+		name: name,
+		opts: options.withDefaults(),
+	}
+}
+
+func (s *ServiceTokenSource) Token(context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Until(s.expiresAt) > s.opts.RefreshBefore {
+		return s.token, nil
+	}
+
+	expiresAt := time.Now().Add(s.opts.TTL)
+	token, err := GenerateToken(s.config, s.name, s.opts.TTL, serviceTokenClaims{Scope: strings.Join(s.opts.Scopes, " ")})
+	if err != nil {
+		return "", err
+	}
+
+	s.token, s.expiresAt = token, expiresAt
+
+	return token, nil
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (s *ServiceTokenSource) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	token, err := s.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. Tokens are only sent over TLS connections;
+// use UnaryClientInterceptor on plaintext ones, e.g. behind a service mesh.
+func (s *ServiceTokenSource) RequireTransportSecurity() bool {
+	return true
+}
+
+// TokenTransport is an http.RoundTripper attaching a bearer token from Source to every request.
+type TokenTransport struct {
+	Source TokenSource
+	// Base defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+func (t *TokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.Source.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("getting service token failed: %w", err)
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// a RoundTripper must not modify the request it was given
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return base.RoundTrip(req)
+}
+
+// TrustedService is a service allowed to call this one, with the public keys its tokens are verified with.
+type TrustedService struct {
+	Name string
+	// Scopes the service may request. Tokens requesting others are rejected.
+	Scopes []string
+	Keys   []TrustedKey
+}
+
+// TrustedKey is a public key of a trusted service. Key IDs must be unique across services.
+type TrustedKey struct {
+	ID string
+	// Algorithm is one of RS256, ES256 and EdDSA.
+	Algorithm string
+	// PEM is a PKIX public key.
+	PEM string
+}
+
+// ServiceTrustFromViper reads the trust list from a viper key, "services.trusted" when empty, e.g.
+//
+//	services:
+//	  trusted:
+//	    - name: billing
+//	      scopes: [invoices.read]
+//	      keys:
+//	        - id: billing-2024-06
+//	          algorithm: EdDSA
+//	          pem: "-----BEGIN PUBLIC KEY-----\n..."
+func ServiceTrustFromViper(key string) ([]TrustedService, error) {
+	if key == "" {
+		key = defaultServiceTrustKey
+	}
+
+	var trusted []TrustedService
+	if err := viper.UnmarshalKey(key, &trusted); err != nil {
+		return nil, fmt.Errorf("failed to load trusted services %q : %v", key, err)
+	}
+
+	return trusted, nil
+}
+
+// ServiceVerifierOptions configures a ServiceVerifier.
+type ServiceVerifierOptions struct {
+	// Leeway is the tolerance applied to the time claims.
+	Leeway time.Duration
+	// MaxTTL is the longest lifetime a token may have been minted with. Defaults to 10 minutes.
+	MaxTTL time.Duration
+}
+
+// ServiceVerifier authenticates the calls of other services, accepting the tokens that are intended for this
+// service and signed by a trusted service with one of its own keys.
+type ServiceVerifier struct {
+	name string
+	opts ServiceVerifierOptions
+
+	mu     sync.RWMutex
+	keys   *KeySet
+	owners map[string]TrustedService
+}
+
+// NewServiceVerifier creates a ServiceVerifier for the service name, trusting the given services.
+func NewServiceVerifier(name string, trusted []TrustedService, options *ServiceVerifierOptions) (*ServiceVerifier, error) {
+	v := &ServiceVerifier{name: name}
+	if options != nil {
+		v.opts = *options
+	}
+	if v.opts.MaxTTL <= 0 {
+		v.opts.MaxTTL = defaultServiceMaxTTL
+	}
+
+	if err := v.Update(trusted); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// Update replaces the trusted services, e.g. after the config changed. On error the current ones are kept.
+func (v *ServiceVerifier) Update(trusted []TrustedService) error {
+	keys := &KeySet{keys: map[string]Key{}}
+	owners := map[string]TrustedService{}
+
+	for _, service := range trusted {
+		for _, trustedKey := range service.Keys {
+			if _, ok := owners[trustedKey.ID]; ok {
+				return fmt.Errorf("failed to trust service %s : duplicate key id %q", service.Name, trustedKey.ID)
+			}
+
+			key, err := ParseKeyPEM(trustedKey.ID, trustedKey.Algorithm, []byte(trustedKey.PEM))
+			if err != nil {
+				return fmt.Errorf("failed to trust service %s : %w", service.Name, err)
+			}
+			if key.Private != nil || key.Algorithm == jwt.SigningMethodHS256.Alg() {
+				return fmt.Errorf("failed to trust service %s : key %s is not a public key", service.Name, key.ID)
+			}
+			if err = keys.Add(key); err != nil {
+				return fmt.Errorf("failed to trust service %s : %w", service.Name, err)
+			}
+
+			owners[key.ID] = service
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.keys, v.owners = keys, owners
+
+	return nil
+}
+
+// Verify validates the token of a calling service and returns its claims. Errors wrap ErrInvalidToken,
+// and also ErrUntrustedService when the caller is not trusted or requests scopes it was not granted.
+func (v *ServiceVerifier) Verify(ctx context.Context, token string) (*Claims[ServiceClaims], error) {
+	unverified, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return nil, fmt.Errorf("%w : %w", ErrInvalidToken, err)
+	}
+	kid, _ := unverified.Header["kid"].(string)
+
+	v.mu.RLock()
+	keys := v.keys
+	owner, ok := v.owners[kid]
+	v.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w : %w : key %q", ErrInvalidToken, ErrUntrustedService, kid)
+	}
+
+	claims, err := ValidateToken[ServiceClaims](ctx, TokenConfig{
+		Keys:     keys,
+		Audience: []string{v.name},
+		Leeway:   v.opts.Leeway,
+	}, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// a trusted service can only speak for itself, with the key IDs it owns
+	if claims.Issuer != owner.Name || claims.Subject != owner.Name {
+		return nil, fmt.Errorf("%w : %w : %s signed as %s", ErrInvalidToken, ErrUntrustedService, owner.Name, claims.Subject)
+	}
+	if claims.ExpiresAt.Sub(claims.IssuedAt) > v.opts.MaxTTL {
+		return nil, fmt.Errorf("%w : lifetime exceeds %s", ErrInvalidToken, v.opts.MaxTTL)
+	}
+	for _, scope := range claims.Scopes {
+		if !contains(owner.Scopes, scope) {
+			return nil, fmt.Errorf("%w : %w : scope %q not granted to %s", ErrInvalidToken, ErrUntrustedService, scope, owner.Name)
+		}
+	}
+
+	return claims, nil
+}
+
+// UnaryServerInterceptor authenticates unary calls from trusted services and enforces the method rules,
+// like UnaryServerInterceptor does for user tokens. Claims are available through ClaimsFromContext[ServiceClaims]
+// and the caller through CallerService.
+func (v *ServiceVerifier) UnaryServerInterceptor(options *GRPCOptions) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorizeCall(ctx, v.Verify, options.rule(info.FullMethod))
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func (v *ServiceVerifier) StreamServerInterceptor(options *GRPCOptions) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorizeCall(ss.Context(), v.Verify, options.rule(info.FullMethod))
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// Middleware authenticates requests from trusted services, like Middleware does for user tokens.
+func (v *ServiceVerifier) Middleware(options *MiddlewareOptions) gin.HandlerFunc {
+	return middleware(v.Verify, options)
+}
+
+// CallerService returns the name of the service that made the authenticated call.
+func CallerService(ctx context.Context) (string, bool) {
+	claims, ok := ClaimsFromContext[ServiceClaims](ctx)
+	if !ok {
+		return "", false
+	}
+
+	return claims.Subject, true
+}
@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrNoRevocations   = errors.New("token config has no revocation list")
+)
+
+// Session is a login of a user. Its ID is the ID (jti) of the token issued for it.
+type Session struct {
+	ID        string `gorm:"primaryKey"`
+	UserID    string `gorm:"index;not null"`
+	UserAgent string
+	IP        string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+func (Session) TableName() string {
+	return "auth_sessions"
+}
+
+// Active reports whether the session is neither revoked nor expired.
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// SessionInfo describes the client a session is created for.
+type SessionInfo struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionStore keeps sessions. Get must return ErrSessionNotFound for an unknown session.
+type SessionStore interface {
+	Create(ctx context.Context, session *Session) error
+	Get(ctx context.Context, id string) (*Session, error)
+	// ListByUser returns the active sessions of a user, most recent first.
+	ListByUser(ctx context.Context, userID string) ([]Session, error)
+	MarkRevoked(ctx context.Context, id string, at time.Time) error
+}
+
+// Sessions issues tokens tied to sessions, which can be listed and revoked individually or per user.
+type Sessions struct {
+	config TokenConfig
+	store  SessionStore
+}
+
+// NewSessions creates a Sessions issuing tokens with c. Revoked sessions are recorded in c.Revocations,
+// so that ValidateToken rejects their tokens.
+func NewSessions(c TokenConfig, store SessionStore) (*Sessions, error) {
+	if c.Revocations == nil {
+		return nil, ErrNoRevocations
+	}
+
+	return &Sessions{
+		config: c,
+		store:  store,
+	}, nil
+}
+
+// CreateSession starts a session for a user and returns it with its token, generated like GenerateToken does.
+func CreateSession[T any](ctx context.Context,
+	s *Sessions,
+	userID string,
+	ttl time.Duration,
+	custom T,
+	info SessionInfo) (string, *Session, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", nil, fmt.Errorf("creating session failed: %w", err)
+	}
+
+	now := time.Now().UTC()
+	session := &Session{
+		ID:        id,
+		UserID:    userID,
+		UserAgent: info.UserAgent,
+		IP:        info.IP,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	token, err := generateToken(s.config, id, userID, ttl, custom)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err = s.store.Create(ctx, session); err != nil {
+		return "", nil, fmt.Errorf("creating session failed: %w", err)
+	}
+
+	return token, session, nil
+}
+
+// Get returns a session, active or not.
+func (s *Sessions) Get(ctx context.Context, id string) (*Session, error) {
+	return s.store.Get(ctx, id)
+}
+
+// List returns the active sessions of a user, most recent first.
+func (s *Sessions) List(ctx context.Context, userID string) ([]Session, error) {
+	return s.store.ListByUser(ctx, userID)
+}
+
+// Revoke ends a session, e.g. on logout.
+func (s *Sessions) Revoke(ctx context.Context, id string) error {
+	session, err := s.store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.revoke(ctx, session)
+}
+
+// RevokeAll ends every session of a user, e.g. after a password change or a compromise.
+func (s *Sessions) RevokeAll(ctx context.Context, userID string) error {
+	sessions, err := s.store.ListByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for i := range sessions {
+		if err = s.revoke(ctx, &sessions[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Sessions) revoke(ctx context.Context, session *Session) error {
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	// the token is revoked first, so that a failure leaves the session listed and revocable again
+	if err := s.config.Revocations.Revoke(ctx, session.ID, session.ExpiresAt); err != nil {
+		return err
+	}
+
+	if err := s.store.MarkRevoked(ctx, session.ID, time.Now().UTC()); err != nil {
+		return fmt.Errorf("revoking session failed: %w", err)
+	}
+
+	return nil
+}
+
+// MemorySessionStore keeps sessions in memory. It only fits a single instance.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: map[string]Session{}}
+}
+
+func (m *MemorySessionStore) Create(_ context.Context, session *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[session.ID] = *session
+
+	return nil
+}
+
+func (m *MemorySessionStore) Get(_ context.Context, id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	return &session, nil
+}
+
+func (m *MemorySessionStore) ListByUser(_ context.Context, userID string) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var res []Session
+	for id, session := range m.sessions {
+		if time.Now().After(session.ExpiresAt) {
+			delete(m.sessions, id)
+			continue
+		}
+		if session.UserID == userID && session.RevokedAt == nil {
+			res = append(res, session)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].CreatedAt.After(res[j].CreatedAt)
+	})
+
+	return res, nil
+}
+
+func (m *MemorySessionStore) MarkRevoked(_ context.Context, id string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	session.RevokedAt = &at
+	m.sessions[id] = session
+
+	return nil
+}
+
+// GormSessionStore keeps sessions in Postgres.
+type GormSessionStore struct {
+	db *gorm.DB
+}
+
+func NewGormSessionStore(db *gorm.DB) *GormSessionStore {
+	return &GormSessionStore{db: db}
+}
+
+// Migrate creates or updates the table holding sessions.
+func (g *GormSessionStore) Migrate(ctx context.Context) error {
+	return g.db.WithContext(ctx).AutoMigrate(&Session{})
+}
+
+func (g *GormSessionStore) Create(ctx context.Context, session *Session) error {
+	return g.db.WithContext(ctx).Create(session).Error
+}
+
+func (g *GormSessionStore) Get(ctx context.Context, id string) (*Session, error) {
+	var session Session
+	err := g.db.WithContext(ctx).Where("id = ?", id).Take(&session).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (g *GormSessionStore) ListByUser(ctx context.Context, userID string) ([]Session, error) {
+	var sessions []Session
+	err := g.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+func (g *GormSessionStore) MarkRevoked(ctx context.Context, id string, at time.Time) error {
+	res := g.db.WithContext(ctx).Model(&Session{}).Where("id = ?", id).Update("revoked_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// Prune deletes sessions that expired before a cutoff and returns how many were deleted.
+func (g *GormSessionStore) Prune(ctx context.Context, expiredBefore time.Time) (int64, error) {
+	res := g.db.WithContext(ctx).Where("expires_at < ?", expiredBefore).Delete(&Session{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("pruning sessions failed: %w", res.Error)
+	}
+
+	return res.RowsAffected, nil
+}
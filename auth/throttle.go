@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAccountMaxFailures = 5
+	defaultIPMaxFailures      = 20
+	defaultThrottleWindow     = 15 * time.Minute
+)
+
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// ThrottledError is returned while logins are blocked for an account or an IP.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// LoginThrottle counts failed logins per key and blocks a key once it reached its limit. Every attempt is
+// counted as a failure from its start, so that concurrent attempts cannot get past the limit, and is released
+// once it turns out not to be one.
+type LoginThrottle interface {
+	// Attempt counts an attempt for a key unless the key is blocked, in which case it returns how long it
+	// stays blocked. Checking and counting must be atomic.
+	Attempt(ctx context.Context, key string, maxFailures int) (time.Duration, error)
+	// Release uncounts an attempt that did not fail.
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+// ThrottleOptions are the limits of failed logins. A key is blocked once it failed MaxFailures times within
+// Window, until the oldest of those failures is older than Window.
+type ThrottleOptions struct {
+	// AccountMaxFailures defaults to 5.
+	AccountMaxFailures int
+	// IPMaxFailures is higher than AccountMaxFailures, since many users can share an IP. Defaults to 20.
+	IPMaxFailures int
+}
+
+func (o *ThrottleOptions) withDefaults() ThrottleOptions {
+	var res ThrottleOptions
+	if o != nil {
+		res = *o
+	}
+	if res.AccountMaxFailures <= 0 {
+		res.AccountMaxFailures = defaultAccountMaxFailures
+	}
+	if res.IPMaxFailures <= 0 {
+		res.IPMaxFailures = defaultIPMaxFailures
+	}
+
+	return res
+}
+
+// MemoryLoginThrottle keeps failures in memory. It only fits a single instance.
+type MemoryLoginThrottle struct {
+	window time.Duration
+
+	mu        sync.Mutex
+	failures  map[string][]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryLoginThrottle creates a MemoryLoginThrottle counting failures within window, 15 minutes if zero.
+func NewMemoryLoginThrottle(window time.Duration) *MemoryLoginThrottle {
+	if window <= 0 {
+		window = defaultThrottleWindow
+	}
+
+	return &MemoryLoginThrottle{
+		window:   window,
+		failures: map[string][]time.Time{},
+	}
+}
+
+func (m *MemoryLoginThrottle) Attempt(_ context.Context, key string, maxFailures int) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	failures := m.recent(key)
+	if len(failures) >= maxFailures {
+		return failures[len(failures)-maxFailures].Add(m.window).Sub(time.Now()), nil
+	}
+
+	m.failures[key] = append(failures, time.Now())
+
+	// keys that are never seen again, e.g. scanning IPs, are dropped at most once per window
+	if time.Since(m.lastSweep) > m.window {
+		for k := range m.failures {
+			m.recent(k)
+		}
+		m.lastSweep = time.Now()
+	}
+
+	return 0, nil
+}
+
+func (m *MemoryLoginThrottle) Release(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if failures := m.recent(key); len(failures) > 1 {
+		m.failures[key] = failures[:len(failures)-1]
+	} else {
+		delete(m.failures, key)
+	}
+
+	return nil
+}
+
+func (m *MemoryLoginThrottle) Reset(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+
+	return nil
+}
+
+// recent drops the failures of a key older than the window and returns the others, oldest first.
+func (m *MemoryLoginThrottle) recent(key string) []time.Time {
+	failures := m.failures[key]
+	cutoff := time.Now().Add(-m.window)
+
+	i := 0
+	for i < len(failures) && !failures[i].After(cutoff) {
+		i++
+	}
+	failures = failures[i:]
+
+	if len(failures) == 0 {
+		delete(m.failures, key)
+		return nil
+	}
+	m.failures[key] = failures
+
+	return failures
+}
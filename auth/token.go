@@ -1,25 +1,119 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(ttl time.Duration, payload interface{}, secretJWTKey string) (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrRevokedToken = errors.New("token has been revoked")
+)
 
-	now := time.Now().UTC()
-	claims := token.Claims.(jwt.MapClaims)
+// registeredClaims are the claim names set from TokenConfig and Claims rather than from custom claims.
+var registeredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// TokenConfig configures how tokens are signed and validated.
+type TokenConfig struct {
+	Keys *KeySet
+	// Issuer is set on generated tokens and, when not empty, required on validated ones.
+	Issuer string
+	// Audience is set on generated tokens. When not empty, validated tokens must be intended for one of them.
+	Audience []string
+	// Leeway is the tolerance applied to the time claims of validated tokens.
+	Leeway time.Duration
+	// Revocations, when set, is consulted for every validated token. Wrapping it in a CachedRevocationList
+	// avoids a lookup per request.
+	Revocations RevocationList
+}
+
+// Claims are the claims of a validated token. Custom holds the claims that are not registered ones.
+type Claims[T any] struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+	NotBefore time.Time
+	ID        string
+	// Scopes are read from the "scope" claim, a space-separated string, or the "scp" claim, an array.
+	// Roles are read from the "roles" claim. Both are also available in Custom when T declares them.
+	Scopes []string
+	Roles  []string
+	Custom T
+}
+
+// HasScopes reports whether the token was granted every scope.
+func (c *Claims[T]) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !contains(c.Scopes, scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasRole reports whether the token holds at least one of the roles.
+func (c *Claims[T]) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if contains(c.Roles, role) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GenerateToken signs a token for subject valid for ttl with the active key of c.Keys. Custom claims are
+// encoded as top-level claims, so T must encode to a JSON object, typically a struct with json tags.
+// Every token gets a random ID (jti) it can be revoked by.
+func GenerateToken[T any](c TokenConfig, subject string, ttl time.Duration, custom T) (string, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", fmt.Errorf("generating JWT Token failed: %w", err)
+	}
+
+	return generateToken(c, id, subject, ttl, custom)
+}
+
+func generateToken(c TokenConfig, id string, subject string, ttl time.Duration, custom interface{}) (string, error) {
+	key := c.Keys.signingKey()
+	method, err := key.signingMethod()
+	if err != nil {
+		return "", fmt.Errorf("generating JWT Token failed: %w", err)
+	}
 
-	claims["sub"] = payload
+	claims, err := toMapClaims(custom)
+	if err != nil {
+		return "", fmt.Errorf("generating JWT Token failed: %w", err)
+	}
+
+	now := time.Now().UTC()
+	claims["sub"] = subject
 	claims["exp"] = now.Add(ttl).Unix()
 	claims["iat"] = now.Unix()
 	claims["nbf"] = now.Unix()
+	claims["jti"] = id
+	if c.Issuer != "" {
+		claims["iss"] = c.Issuer
+	}
+	if len(c.Audience) > 0 {
+		claims["aud"] = c.Audience
+	}
 
-	tokenString, err := token.SignedString([]byte(secretJWTKey))
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
 
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", fmt.Errorf("generating JWT Token failed: %w", err)
 	}
@@ -27,22 +121,153 @@ func GenerateToken(ttl time.Duration, payload interface{}, secretJWTKey string)
 	return tokenString, nil
 }
 
-func ValidateToken(token string, signedJWTKey string) (interface{}, error) {
-	tok, err := jwt.Parse(token, func(jwtToken *jwt.Token) (interface{}, error) {
-		if _, ok := jwtToken.Method.(*jwt.SigningMethodHMAC); !ok {
+// ValidateToken checks the signature of a token against c.Keys, its time claims, issuer and audience,
+// and that it has not been revoked, and returns its claims.
+func ValidateToken[T any](ctx context.Context, c TokenConfig, token string) (*Claims[T], error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(c.Keys.algorithms()),
+		jwt.WithLeeway(c.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if c.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(c.Issuer))
+	}
+
+	mapClaims := jwt.MapClaims{}
+	_, err := jwt.NewParser(opts...).ParseWithClaims(token, mapClaims, func(jwtToken *jwt.Token) (interface{}, error) {
+		kid, _ := jwtToken.Header["kid"].(string)
+		key, ok := c.Keys.key(kid)
+		if !ok {
+			return nil, fmt.Errorf("%w : %q", ErrUnknownKey, kid)
+		}
+
+		// the algorithm is bound to the key so that a token cannot pick how it is verified
+		if jwtToken.Method.Alg() != key.Algorithm {
 			return nil, fmt.Errorf("unexpected method: %s", jwtToken.Header["alg"])
 		}
 
-		return []byte(signedJWTKey), nil
+		return key.verificationKey(), nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("invalidate token: %w", err)
+		return nil, fmt.Errorf("%w : %w", ErrInvalidToken, err)
 	}
 
-	claims, ok := tok.Claims.(jwt.MapClaims)
-	if !ok || !tok.Valid {
-		return nil, fmt.Errorf("invalid token claim")
+	claims, err := fromMapClaims[T](mapClaims)
+	if err != nil {
+		return nil, fmt.Errorf("%w : %w", ErrInvalidToken, err)
+	}
+
+	if len(c.Audience) > 0 && !intendedFor(claims.Audience, c.Audience) {
+		return nil, fmt.Errorf("%w : %w", ErrInvalidToken, jwt.ErrTokenInvalidAudience)
+	}
+
+	if c.Revocations != nil && claims.ID != "" {
+		revoked, err := c.Revocations.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return nil, fmt.Errorf("checking token revocation failed: %w", err)
+		}
+		if revoked {
+			return nil, fmt.Errorf("%w : %w", ErrInvalidToken, ErrRevokedToken)
+		}
+	}
+
+	return claims, nil
+}
+
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func toMapClaims(custom interface{}) (jwt.MapClaims, error) {
+	b, err := json.Marshal(custom)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.MapClaims{}
+	if string(b) == "null" {
+		return claims, nil
+	}
+	if err = json.Unmarshal(b, &claims); err != nil {
+		return nil, fmt.Errorf("custom claims must encode to a JSON object: %w", err)
+	}
+
+	for _, name := range registeredClaims {
+		delete(claims, name)
+	}
+
+	return claims, nil
+}
+
+func fromMapClaims[T any](mapClaims jwt.MapClaims) (*Claims[T], error) {
+	claims := &Claims[T]{}
+	claims.Issuer, _ = mapClaims.GetIssuer()
+	claims.Subject, _ = mapClaims.GetSubject()
+	claims.Audience, _ = mapClaims.GetAudience()
+	claims.ID, _ = mapClaims["jti"].(string)
+	claims.Scopes = stringsClaim(mapClaims, "scope", "scp")
+	claims.Roles = stringsClaim(mapClaims, "roles")
+
+	if exp, _ := mapClaims.GetExpirationTime(); exp != nil {
+		claims.ExpiresAt = exp.Time
+	}
+	if iat, _ := mapClaims.GetIssuedAt(); iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+	if nbf, _ := mapClaims.GetNotBefore(); nbf != nil {
+		claims.NotBefore = nbf.Time
+	}
+
+	custom := jwt.MapClaims{}
+	for k, v := range mapClaims {
+		if !contains(registeredClaims, k) {
+			custom[k] = v
+		}
+	}
+
+	b, err := json.Marshal(custom)
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &claims.Custom); err != nil {
+		return nil, fmt.Errorf("invalid token claim: %w", err)
+	}
+
+	return claims, nil
+}
+
+// stringsClaim reads the first present claim as a list of strings, splitting a string on spaces.
+func stringsClaim(mapClaims jwt.MapClaims, names ...string) []string {
+	for _, name := range names {
+		switch v := mapClaims[name].(type) {
+		case string:
+			return strings.Fields(v)
+		case []interface{}:
+			res := make([]string, 0, len(v))
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					res = append(res, s)
+				}
+			}
+			return res
+		}
+	}
+
+	return nil
+}
+
+func intendedFor(audience []string, accepted []string) bool {
+	for _, aud := range audience {
+		if contains(accepted, aud) {
+			return true
+		}
 	}
 
-	return claims["sub"], nil
+	return false
 }
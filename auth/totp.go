@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP follows RFC 6238 with the parameters every authenticator app supports: HMAC-SHA1, 6 digits and 30 second steps.
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// totpSkew is the number of steps a code may be early or late, to allow for clock drift.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewTOTPSecret returns a random base32 secret to enrol an authenticator with.
+func NewTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating totp secret failed: %w", err)
+	}
+
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPURI returns the otpauth:// URI of a secret, which authenticator apps read from a QR code.
+func TOTPURI(issuer string, account string, secret string) string {
+	label := account
+	query := url.Values{}
+	query.Set("secret", secret)
+	if issuer != "" {
+		label = issuer + ":" + account
+		query.Set("issuer", issuer)
+	}
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	return "otpauth://totp/" + url.PathEscape(label) + "?" + query.Encode()
+}
+
+// ValidateTOTP checks a code against a secret at a time and returns the time step it matched. Codes of steps up to
+// lastStep are rejected, so that passing the step of the previous successful validation prevents replays.
+func ValidateTOTP(secret string, code string, lastStep int64, at time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := at.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation, RFC 4226 section 5.3
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// NewRecoveryCodes returns single-use recovery codes formatted as "xxxxx-xxxxx", and their hashes to store.
+// The codes are hashed like passwords, since they are too short to withstand a brute force of a fast hash.
+func NewRecoveryCodes(hasher *PasswordHasher) ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("generating recovery codes failed: %w", err)
+		}
+
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+
+		hash, err := hasher.Hash(normalizeRecoveryCode(codes[i]))
+		if err != nil {
+			return nil, nil, fmt.Errorf("generating recovery codes failed: %w", err)
+		}
+		hashes[i] = hash
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores the case and the separator of a code, which users tend to mistype.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
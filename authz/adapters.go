@@ -0,0 +1,126 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/badfan/go-toolkit/auth"
+	"github.com/badfan/go-toolkit/tracer"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SubjectFunc returns the subject of an authenticated request, and false when it is not authenticated.
+type SubjectFunc func(ctx context.Context) (Subject, bool)
+
+// FromClaims returns a SubjectFunc reading the claims put into the context by the auth middleware or
+// interceptors. The subject ID and roles come from the token; attributes, e.g. a tenant ID, are extracted
+// from the claims by the optional attributes function.
+func FromClaims[T any](attributes func(*auth.Claims[T]) map[string]string) SubjectFunc {
+	return func(ctx context.Context) (Subject, bool) {
+		claims, ok := auth.ClaimsFromContext[T](ctx)
+		if !ok {
+			return Subject{}, false
+		}
+
+		subject := Subject{ID: claims.Subject, Roles: claims.Roles}
+		if attributes != nil {
+			subject.Attributes = attributes(claims)
+		}
+
+		return subject, true
+	}
+}
+
+// ResourceFunc returns the resource a request acts on, e.g. from its path parameters.
+type ResourceFunc func(c *gin.Context) (Resource, error)
+
+// Middleware authorizes action on the resource of each request. It must run after the auth middleware.
+// Requests are rejected with a 401 when unauthenticated and with a 403 when denied.
+func Middleware(a *Authorizer, subject SubjectFunc, action string, resource ResourceFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, ok := subject(c.Request.Context())
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+			return
+		}
+
+		r, err := resource(c)
+		if err != nil {
+			tracer.AddSpanError(tracer.SpanFromContext(c.Request.Context()), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+
+		// the reason of a denial stays in the span event, so that clients learn nothing about the policy
+		err = a.Authorize(c.Request.Context(), s, action, r)
+		if errors.Is(err, ErrForbidden) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		if err != nil {
+			tracer.AddSpanError(tracer.SpanFromContext(c.Request.Context()), err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// CallFunc returns the action and resource of a gRPC call. Req is nil for streams. An empty action skips
+// authorization, e.g. for public methods.
+type CallFunc func(ctx context.Context, fullMethod string, req interface{}) (string, Resource, error)
+
+// UnaryServerInterceptor authorizes unary calls. It must run after the auth interceptor. Calls fail with
+// Unauthenticated when unauthenticated and with PermissionDenied when denied.
+func UnaryServerInterceptor(a *Authorizer, subject SubjectFunc, call CallFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorizeCall(ctx, a, subject, call, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of UnaryServerInterceptor.
+func StreamServerInterceptor(a *Authorizer, subject SubjectFunc, call CallFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorizeCall(ss.Context(), a, subject, call, info.FullMethod, nil); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func authorizeCall(ctx context.Context, a *Authorizer, subject SubjectFunc, call CallFunc, fullMethod string, req interface{}) error {
+	action, resource, err := call(ctx, fullMethod, req)
+	if err != nil {
+		tracer.AddSpanError(tracer.SpanFromContext(ctx), err)
+		return status.Error(codes.Internal, "internal error")
+	}
+	if action == "" {
+		return nil
+	}
+
+	s, ok := subject(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	err = a.Authorize(ctx, s, action, resource)
+	if errors.Is(err, ErrForbidden) {
+		return status.Error(codes.PermissionDenied, "forbidden")
+	}
+	if err != nil {
+		tracer.AddSpanError(tracer.SpanFromContext(ctx), err)
+		return status.Error(codes.Internal, "internal error")
+	}
+
+	return nil
+}
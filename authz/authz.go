@@ -0,0 +1,164 @@
+// Package authz decides whether a subject may perform an action on a resource, following a policy of roles and
+// attribute-based rules kept in the service configuration.
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/badfan/go-toolkit/tracer"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultPolicyKey     = "authz"
+	defaultRefreshPeriod = 10 * time.Second
+)
+
+// ErrForbidden is returned when a policy denies a request.
+var ErrForbidden = errors.New("forbidden")
+
+// DeniedError is a request denied by the policy.
+type DeniedError struct {
+	Action   string
+	Resource Resource
+	Reason   string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("%s : %s on %s %s : %s", ErrForbidden, e.Action, e.Resource.Type, e.Resource.ID, e.Reason)
+}
+
+func (e *DeniedError) Unwrap() error {
+	return ErrForbidden
+}
+
+// Subject is who performs an action, e.g. a user or a service.
+type Subject struct {
+	ID         string
+	Roles      []string
+	Attributes map[string]string
+}
+
+// Resource is what an action is performed on.
+type Resource struct {
+	Type       string
+	ID         string
+	Attributes map[string]string
+}
+
+// PolicySource provides the current policy.
+type PolicySource interface {
+	Policy() (*Policy, error)
+}
+
+// StaticPolicy is a PolicySource that never changes.
+type StaticPolicy Policy
+
+func (p *StaticPolicy) Policy() (*Policy, error) {
+	return (*Policy)(p), nil
+}
+
+// ViperPolicySource reads the policy from a viper key, which the Firestore config provider keeps up to date.
+// The key is decoded again at most once per refresh period, so policy changes apply within that period.
+type ViperPolicySource struct {
+	key    string
+	period time.Duration
+
+	mu       sync.Mutex
+	policy   *Policy
+	loadedAt time.Time
+}
+
+// NewViperPolicySource creates a ViperPolicySource reading key, "authz" when empty, every period,
+// 10 seconds when zero.
+func NewViperPolicySource(key string, period time.Duration) *ViperPolicySource {
+	if key == "" {
+		key = defaultPolicyKey
+	}
+	if period <= 0 {
+		period = defaultRefreshPeriod
+	}
+
+	return &ViperPolicySource{
+		key:    key,
+		period: period,
+	}
+}
+
+// Policy returns the current policy. When the configured one is invalid, the last valid policy is kept
+// and the error is returned with it.
+func (s *ViperPolicySource) Policy() (*Policy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.policy != nil && time.Since(s.loadedAt) < s.period {
+		return s.policy, nil
+	}
+	s.loadedAt = time.Now()
+
+	var policy Policy
+	err := viper.UnmarshalKey(s.key, &policy)
+	if err == nil {
+		err = policy.Validate()
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to load policy %q : %v", s.key, err)
+		if s.policy == nil {
+			return nil, err
+		}
+
+		return s.policy, err
+	}
+
+	s.policy = &policy
+
+	return s.policy, nil
+}
+
+// Authorizer enforces a policy.
+type Authorizer struct {
+	source PolicySource
+}
+
+func NewAuthorizer(source PolicySource) *Authorizer {
+	return &Authorizer{source: source}
+}
+
+// Authorize returns nil when subject may perform action on resource, and a *DeniedError otherwise.
+// Each decision is recorded as an event of the current span.
+func (a *Authorizer) Authorize(ctx context.Context, subject Subject, action string, resource Resource) error {
+	policy, err := a.source.Policy()
+	if policy == nil {
+		return err
+	}
+	if err != nil {
+		// the previous policy keeps being enforced
+		tracer.AddSpanError(tracer.SpanFromContext(ctx), err)
+	}
+
+	allowed, reason := policy.decide(subject, action, resource)
+
+	tracer.AddSpanEvents(tracer.SpanFromContext(ctx), "authz.decision", map[string]string{
+		"authz.subject":       subject.ID,
+		"authz.action":        action,
+		"authz.resource.type": resource.Type,
+		"authz.resource.id":   resource.ID,
+		"authz.allowed":       strconv.FormatBool(allowed),
+		"authz.reason":        reason,
+	})
+
+	if !allowed {
+		return &DeniedError{
+			Action:   action,
+			Resource: resource,
+			Reason:   reason,
+		}
+	}
+
+	return nil
+}
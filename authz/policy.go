@@ -0,0 +1,238 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+const wildcard = "*"
+
+// Effects of a rule.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Operators of a condition.
+const (
+	OpEqual    = "eq"
+	OpNotEqual = "ne"
+	// OpIn is true when the left operand is one of the comma-separated values of the right one.
+	OpIn = "in"
+)
+
+// Policy is the authorization model of a service: roles granting permissions (RBAC), and rules allowing or
+// denying actions depending on the attributes of the subject and the resource (ABAC).
+//
+// A request is denied when a deny rule matches it. Otherwise it is allowed when a role of the subject grants
+// the permission "<resource type>:<action>" or an allow rule matches it, and denied by default.
+type Policy struct {
+	Roles map[string]Role `mapstructure:"roles" json:"roles"`
+	Rules []Rule          `mapstructure:"rules" json:"rules"`
+}
+
+// Role grants permissions of the form "<resource type>:<action>", where either part can be "*".
+type Role struct {
+	Permissions []string `mapstructure:"permissions" json:"permissions"`
+	// Inherits lists roles whose permissions are granted too.
+	Inherits []string `mapstructure:"inherits" json:"inherits"`
+}
+
+// Rule allows or denies actions on resource types when all its conditions hold.
+type Rule struct {
+	Name   string `mapstructure:"name" json:"name"`
+	Effect string `mapstructure:"effect" json:"effect"`
+	// Roles restricts the rule to subjects holding one of them. Empty means every subject.
+	Roles     []string    `mapstructure:"roles" json:"roles"`
+	Actions   []string    `mapstructure:"actions" json:"actions"`
+	Resources []string    `mapstructure:"resources" json:"resources"`
+	When      []Condition `mapstructure:"when" json:"when"`
+}
+
+// Condition compares two operands, each either an attribute reference ("subject.<name>" or "resource.<name>",
+// with "subject.id", "resource.id" and "resource.type" always set) or a literal. A condition referencing a missing
+// attribute fails closed: it never holds in an allow rule and always holds in a deny rule.
+//
+// For instance, ownership is {Left: "subject.id", Op: "eq", Right: "resource.owner_id"}, and tenant scoping is
+// a deny rule with {Left: "subject.tenant_id", Op: "ne", Right: "resource.tenant_id"}.
+type Condition struct {
+	Left  string `mapstructure:"left" json:"left"`
+	Op    string `mapstructure:"op" json:"op"`
+	Right string `mapstructure:"right" json:"right"`
+}
+
+// Validate checks the policy for unknown effects, operators and roles and for inheritance cycles.
+func (p *Policy) Validate() error {
+	for name := range p.Roles {
+		if _, err := p.permissions(name, map[string]bool{}); err != nil {
+			return err
+		}
+	}
+
+	for i, rule := range p.Rules {
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %d %q : unknown effect %q", i, rule.Name, rule.Effect)
+		}
+		for _, c := range rule.When {
+			if c.Op != OpEqual && c.Op != OpNotEqual && c.Op != OpIn {
+				return fmt.Errorf("rule %d %q : unknown operator %q", i, rule.Name, c.Op)
+			}
+		}
+	}
+
+	return nil
+}
+
+// decide evaluates the policy and returns whether the request is allowed and why.
+func (p *Policy) decide(subject Subject, action string, resource Resource) (bool, string) {
+	permission := resource.Type + ":" + action
+
+	for _, rule := range p.Rules {
+		if rule.Effect == EffectDeny && rule.matches(subject, action, resource) {
+			return false, fmt.Sprintf("denied by rule %q", rule.Name)
+		}
+	}
+
+	for _, role := range subject.Roles {
+		permissions, err := p.permissions(role, map[string]bool{})
+		if err != nil {
+			continue
+		}
+		for _, granted := range permissions {
+			if permissionMatches(granted, permission) {
+				return true, fmt.Sprintf("role %q grants %q", role, granted)
+			}
+		}
+	}
+
+	for _, rule := range p.Rules {
+		if rule.Effect == EffectAllow && rule.matches(subject, action, resource) {
+			return true, fmt.Sprintf("allowed by rule %q", rule.Name)
+		}
+	}
+
+	return false, fmt.Sprintf("no role or rule grants %q", permission)
+}
+
+// permissions returns the permissions of a role and the roles it inherits.
+func (p *Policy) permissions(role string, visiting map[string]bool) ([]string, error) {
+	r, ok := p.Roles[role]
+	if !ok {
+		// viper lowercases map keys, so role names read from the config are lowercase
+		r, ok = p.Roles[strings.ToLower(role)]
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+	if visiting[role] {
+		return nil, fmt.Errorf("role %q inherits itself", role)
+	}
+	visiting[role] = true
+	defer delete(visiting, role)
+
+	permissions := append([]string{}, r.Permissions...)
+	for _, inherited := range r.Inherits {
+		more, err := p.permissions(inherited, visiting)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, more...)
+	}
+
+	return permissions, nil
+}
+
+func (r *Rule) matches(subject Subject, action string, resource Resource) bool {
+	if len(r.Roles) > 0 && !anyOf(r.Roles, subject.Roles) {
+		return false
+	}
+	if !matchesAny(r.Actions, action) || !matchesAny(r.Resources, resource.Type) {
+		return false
+	}
+
+	for _, c := range r.When {
+		if !c.holds(subject, resource, r.Effect == EffectDeny) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// holds evaluates the condition, or returns missing when one of its attributes is not set.
+func (c *Condition) holds(subject Subject, resource Resource, missing bool) bool {
+	left, ok := operand(c.Left, subject, resource)
+	if !ok {
+		return missing
+	}
+	right, ok := operand(c.Right, subject, resource)
+	if !ok {
+		return missing
+	}
+
+	switch c.Op {
+	case OpEqual:
+		return left == right
+	case OpNotEqual:
+		return left != right
+	case OpIn:
+		for _, v := range strings.Split(right, ",") {
+			if strings.TrimSpace(v) == left {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func operand(value string, subject Subject, resource Resource) (string, bool) {
+	switch {
+	case value == "subject.id":
+		return subject.ID, true
+	case value == "resource.id":
+		return resource.ID, true
+	case value == "resource.type":
+		return resource.Type, true
+	case strings.HasPrefix(value, "subject."):
+		v, ok := subject.Attributes[strings.TrimPrefix(value, "subject.")]
+		return v, ok
+	case strings.HasPrefix(value, "resource."):
+		v, ok := resource.Attributes[strings.TrimPrefix(value, "resource.")]
+		return v, ok
+	default:
+		return value, true
+	}
+}
+
+func permissionMatches(granted string, permission string) bool {
+	grantedType, grantedAction, ok := strings.Cut(granted, ":")
+	if !ok {
+		grantedAction = wildcard
+	}
+	resourceType, action, _ := strings.Cut(permission, ":")
+
+	return (grantedType == wildcard || grantedType == resourceType) && (grantedAction == wildcard || grantedAction == action)
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if p == wildcard || p == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func anyOf(wanted []string, held []string) bool {
+	for _, w := range wanted {
+		for _, h := range held {
+			if w == h {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,103 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/badfan/go-toolkit/checksum"
+)
+
+var checksumAlgorithms = map[checksum.Algorithm]types.ChecksumAlgorithm{
+	checksum.SHA256: types.ChecksumAlgorithmSha256,
+	checksum.CRC32C: types.ChecksumAlgorithmCrc32c,
+}
+
+// addChecksum computes the checksum of the input body, stores it in the object metadata and asks S3
+// to validate every uploaded part with the same algorithm. Without checksums enabled, S3 still validates
+// CRC32 checksums computed by the SDK, since buckets with object lock refuse uploads without any.
+func (s *S3) addChecksum(input *s3.PutObjectInput) error {
+	if s.checksum == "" {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmCrc32
+		return nil
+	}
+
+	algorithm, ok := checksumAlgorithms[s.checksum]
+	if !ok {
+		return fmt.Errorf("%w : %q", checksum.ErrUnsupportedAlgorithm, string(s.checksum))
+	}
+
+	body, ok := input.Body.(io.ReadSeeker)
+	if !ok {
+		buf, err := io.ReadAll(input.Body)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	start, err := body.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	sum, err := s.checksum.Sum(body)
+	if err != nil {
+		return err
+	}
+
+	if _, err = body.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+
+	if input.Metadata == nil {
+		input.Metadata = make(map[string]string)
+	}
+	input.Metadata[s.checksum.MetadataKey()] = sum
+	input.ChecksumAlgorithm = algorithm
+	input.Body = body
+
+	return nil
+}
+
+// downloadVerified downloads the object sequentially while hashing it and compares the result with
+// the checksum stored in the object metadata. Objects uploaded without a checksum are downloaded unverified.
+func (s *S3) downloadVerified(ctx context.Context, bucketName string, objectKey string, body io.WriterAt) error {
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download object : %v", err)
+	}
+
+	h, err := s.checksum.New()
+	if err != nil {
+		return err
+	}
+
+	writer := checksum.NewHashingWriterAt(body, h)
+	_, err = s.downloader.Download(ctx, writer, &s3.GetObjectInput{
+		Bucket:  aws.String(bucketName),
+		Key:     aws.String(objectKey),
+		IfMatch: head.ETag,
+	}, func(d *manager.Downloader) {
+		// parts must arrive in order to be hashed as a stream
+		d.Concurrency = 1
+	})
+	if err != nil {
+		return fmt.Errorf("failed to download object : %v", err)
+	}
+
+	expected, ok := checksum.Lookup(head.Metadata, s.checksum)
+	if !ok {
+		return nil
+	}
+
+	return checksum.Verify(s.checksum, expected, checksum.Encode(h))
+}
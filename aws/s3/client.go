@@ -3,9 +3,11 @@ package s3
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type IS3Client interface {
@@ -17,4 +19,14 @@ type IS3Client interface {
 	DeleteObjects(ctx context.Context, bucketName string, objectKeys []string) (*s3.DeleteObjectsOutput, error)
 	ListBucketObjects(ctx context.Context, bucketName string) (*s3.ListObjectsV2Output, error)
 	ListBuckets(ctx context.Context) (*s3.ListBucketsOutput, error)
+	UploadLockedObject(ctx context.Context, bucketName string, objectKey string, body io.Reader, lock UploadLock) (*manager.UploadOutput, error)
+	CreateLockedBucket(ctx context.Context, bucketName string, bucketRegion string) (*s3.CreateBucketOutput, error)
+	SetDefaultRetention(ctx context.Context, bucketName string, retention DefaultRetention) error
+	GetDefaultRetention(ctx context.Context, bucketName string) (*DefaultRetention, error)
+	SetObjectRetention(ctx context.Context, bucketName string, objectKey string, versionID string, mode types.ObjectLockRetentionMode, retainUntil time.Time, bypassGovernance bool) error
+	GetObjectRetention(ctx context.Context, bucketName string, objectKey string, versionID string) (*types.ObjectLockRetention, error)
+	SetLegalHold(ctx context.Context, bucketName string, objectKey string, versionID string, on bool) error
+	GetLegalHold(ctx context.Context, bucketName string, objectKey string, versionID string) (bool, error)
+	DeleteObjectVersion(ctx context.Context, bucketName string, objectKey string, versionID string, bypassGovernance bool) error
+	DeleteObjectVersions(ctx context.Context, bucketName string, objects []types.ObjectIdentifier, bypassGovernance bool) (*s3.DeleteObjectsOutput, error)
 }
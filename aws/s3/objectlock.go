@@ -0,0 +1,328 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// ErrObjectLocked is returned when a retention period or a legal hold prevents an object version from being deleted.
+var ErrObjectLocked = errors.New("object is protected by object lock")
+
+// ObjectLockedError describes an object version whose deletion was refused by object lock.
+// It matches ErrObjectLocked with errors.Is.
+type ObjectLockedError struct {
+	Key       string
+	VersionID string
+	Message   string
+}
+
+func (e *ObjectLockedError) Error() string {
+	if e.VersionID != "" {
+		return fmt.Sprintf("%s : %s (version %s) : %s", ErrObjectLocked, e.Key, e.VersionID, e.Message)
+	}
+
+	return fmt.Sprintf("%s : %s : %s", ErrObjectLocked, e.Key, e.Message)
+}
+
+func (e *ObjectLockedError) Unwrap() error {
+	return ErrObjectLocked
+}
+
+// DefaultRetention is the retention applied to every new object version in a bucket.
+// Exactly one of Days and Years must be set.
+type DefaultRetention struct {
+	Mode  types.ObjectLockRetentionMode
+	Days  int32
+	Years int32
+}
+
+// UploadLock is the object lock of a version when it is uploaded. The retention is skipped when Mode is empty,
+// and overrides the default retention of the bucket otherwise.
+type UploadLock struct {
+	Mode        types.ObjectLockMode
+	RetainUntil time.Time
+	LegalHold   bool
+}
+
+// UploadLockedObject puts body's data into an object in a bucket created with object lock enabled,
+// with the given retention and legal hold.
+func (s *S3) UploadLockedObject(ctx context.Context,
+	bucketName string,
+	objectKey string,
+	body io.Reader,
+	lock UploadLock) (*manager.UploadOutput, error) {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+		Body:   body,
+	}
+	if lock.Mode != "" {
+		if lock.RetainUntil.IsZero() {
+			return nil, fmt.Errorf("failed to upload object : retention mode %s needs a retain until date", lock.Mode)
+		}
+		input.ObjectLockMode = lock.Mode
+		input.ObjectLockRetainUntilDate = aws.Time(lock.RetainUntil)
+	}
+	if lock.LegalHold {
+		input.ObjectLockLegalHoldStatus = types.ObjectLockLegalHoldStatusOn
+	}
+
+	return s.uploadObject(ctx, input)
+}
+
+// CreateLockedBucket creates a bucket with object lock enabled. Object lock also enables versioning
+// and cannot be turned off afterwards.
+func (s *S3) CreateLockedBucket(ctx context.Context, bucketName string, bucketRegion string) (*s3.CreateBucketOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := s.client.CreateBucket(ctx, &s3.CreateBucketInput{
+		Bucket: aws.String(bucketName),
+		CreateBucketConfiguration: &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(bucketRegion),
+		},
+		ObjectLockEnabledForBucket: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bucket : %v", err)
+	}
+
+	return res, nil
+}
+
+// SetDefaultRetention sets the default retention of a bucket created with object lock enabled.
+func (s *S3) SetDefaultRetention(ctx context.Context, bucketName string, retention DefaultRetention) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if (retention.Days == 0) == (retention.Years == 0) {
+		return fmt.Errorf("failed to set default retention : exactly one of days and years must be set")
+	}
+
+	defaultRetention := &types.DefaultRetention{Mode: retention.Mode}
+	if retention.Days != 0 {
+		defaultRetention.Days = retention.Days
+	} else {
+		defaultRetention.Years = retention.Years
+	}
+
+	_, err := s.client.PutObjectLockConfiguration(ctx, &s3.PutObjectLockConfigurationInput{
+		Bucket: aws.String(bucketName),
+		ObjectLockConfiguration: &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+			Rule:              &types.ObjectLockRule{DefaultRetention: defaultRetention},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set default retention : %v", err)
+	}
+
+	return nil
+}
+
+// GetDefaultRetention gets the default retention of a bucket. It returns nil if the bucket has none.
+func (s *S3) GetDefaultRetention(ctx context.Context, bucketName string) (*DefaultRetention, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := s.client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default retention : %v", err)
+	}
+
+	if res.ObjectLockConfiguration == nil || res.ObjectLockConfiguration.Rule == nil ||
+		res.ObjectLockConfiguration.Rule.DefaultRetention == nil {
+		return nil, nil
+	}
+
+	retention := res.ObjectLockConfiguration.Rule.DefaultRetention
+
+	return &DefaultRetention{
+		Mode:  retention.Mode,
+		Days:  retention.Days,
+		Years: retention.Years,
+	}, nil
+}
+
+// SetObjectRetention sets the retention of an object version. An empty versionID targets the latest version.
+// Shortening or removing a governance retention requires bypassGovernance; compliance retention can only be extended.
+func (s *S3) SetObjectRetention(ctx context.Context,
+	bucketName string,
+	objectKey string,
+	versionID string,
+	mode types.ObjectLockRetentionMode,
+	retainUntil time.Time,
+	bypassGovernance bool) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.client.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(objectKey),
+		VersionId: optionalString(versionID),
+		Retention: &types.ObjectLockRetention{
+			Mode:            mode,
+			RetainUntilDate: aws.Time(retainUntil),
+		},
+		BypassGovernanceRetention: bypassGovernance,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set object retention : %v", err)
+	}
+
+	return nil
+}
+
+// GetObjectRetention gets the retention of an object version. An empty versionID targets the latest version.
+func (s *S3) GetObjectRetention(ctx context.Context, bucketName string, objectKey string, versionID string) (*types.ObjectLockRetention, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := s.client.GetObjectRetention(ctx, &s3.GetObjectRetentionInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(objectKey),
+		VersionId: optionalString(versionID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object retention : %v", err)
+	}
+
+	return res.Retention, nil
+}
+
+// SetLegalHold places or removes a legal hold on an object version. An empty versionID targets the latest version.
+func (s *S3) SetLegalHold(ctx context.Context, bucketName string, objectKey string, versionID string, on bool) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	status := types.ObjectLockLegalHoldStatusOff
+	if on {
+		status = types.ObjectLockLegalHoldStatusOn
+	}
+
+	_, err := s.client.PutObjectLegalHold(ctx, &s3.PutObjectLegalHoldInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(objectKey),
+		VersionId: optionalString(versionID),
+		LegalHold: &types.ObjectLockLegalHold{Status: status},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set legal hold : %v", err)
+	}
+
+	return nil
+}
+
+// GetLegalHold reports whether a legal hold is placed on an object version. An empty versionID targets the latest version.
+func (s *S3) GetLegalHold(ctx context.Context, bucketName string, objectKey string, versionID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := s.client.GetObjectLegalHold(ctx, &s3.GetObjectLegalHoldInput{
+		Bucket:    aws.String(bucketName),
+		Key:       aws.String(objectKey),
+		VersionId: optionalString(versionID),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get legal hold : %v", err)
+	}
+
+	return res.LegalHold != nil && res.LegalHold.Status == types.ObjectLockLegalHoldStatusOn, nil
+}
+
+// DeleteObjectVersion permanently deletes an object version. An *ObjectLockedError is returned
+// when retention or a legal hold protects it.
+func (s *S3) DeleteObjectVersion(ctx context.Context, bucketName string, objectKey string, versionID string, bypassGovernance bool) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket:                    aws.String(bucketName),
+		Key:                       aws.String(objectKey),
+		VersionId:                 aws.String(versionID),
+		BypassGovernanceRetention: bypassGovernance,
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && isObjectLockDenial(apiErr.ErrorCode(), apiErr.ErrorMessage()) {
+			return &ObjectLockedError{Key: objectKey, VersionID: versionID, Message: apiErr.ErrorMessage()}
+		}
+
+		return fmt.Errorf("failed to delete object version : %v", err)
+	}
+
+	return nil
+}
+
+// DeleteObjectVersions permanently deletes object versions in bulk, identified by key and version ID.
+// An *ObjectLockedError is returned alongside the output when object lock refused the deletion of any of them;
+// the other failures of the output are left to the caller.
+func (s *S3) DeleteObjectVersions(ctx context.Context,
+	bucketName string,
+	objects []types.ObjectIdentifier,
+	bypassGovernance bool) (*s3.DeleteObjectsOutput, error) {
+	return s.deleteObjects(ctx, bucketName, objects, bypassGovernance)
+}
+
+func (s *S3) deleteObjects(ctx context.Context,
+	bucketName string,
+	objects []types.ObjectIdentifier,
+	bypassGovernance bool) (*s3.DeleteObjectsOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket:                    aws.String(bucketName),
+		Delete:                    &types.Delete{Objects: objects},
+		BypassGovernanceRetention: bypassGovernance,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete objects : %v", err)
+	}
+
+	if err = objectLockedError(res.Errors); err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// objectLockedError returns an *ObjectLockedError for the first key of a DeleteObjects call refused by object lock.
+func objectLockedError(errs []types.Error) error {
+	for _, e := range errs {
+		if isObjectLockDenial(aws.ToString(e.Code), aws.ToString(e.Message)) {
+			return &ObjectLockedError{
+				Key:       aws.ToString(e.Key),
+				VersionID: aws.ToString(e.VersionId),
+				Message:   aws.ToString(e.Message),
+			}
+		}
+	}
+
+	return nil
+}
+
+// isObjectLockDenial tells object lock refusals apart from other access denials, which share the same error code.
+func isObjectLockDenial(code string, message string) bool {
+	return code == "AccessDenied" && strings.Contains(strings.ToLower(message), "object lock")
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return aws.String(s)
+}
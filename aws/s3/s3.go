@@ -13,6 +13,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/badfan/go-toolkit/checksum"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-sdk-go-v2/otelaws"
 )
 
@@ -21,11 +22,14 @@ type S3 struct {
 	uploader   *manager.Uploader
 	downloader *manager.Downloader
 	timeout    time.Duration
+	checksum   checksum.Algorithm
 }
 
 type S3Config struct {
 	Address string
 	Region  string
+	// Checksum enables end-to-end integrity verification of objects. Empty disables it.
+	Checksum checksum.Algorithm
 }
 
 func NewS3(ctx context.Context, c S3Config, timeout time.Duration) (*S3, error) {
@@ -61,6 +65,7 @@ func NewS3(ctx context.Context, c S3Config, timeout time.Duration) (*S3, error)
 		uploader:   manager.NewUploader(client),
 		downloader: manager.NewDownloader(client),
 		timeout:    timeout,
+		checksum:   c.Checksum,
 	}, nil
 }
 
@@ -98,15 +103,24 @@ func (s *S3) DeleteBucket(ctx context.Context, bucketName string) error {
 }
 
 // UploadObject puts body's data into an object in a bucket.
+// When checksums are enabled, body is read twice if it is an io.ReadSeeker and buffered in memory otherwise.
 func (s *S3) UploadObject(ctx context.Context, bucketName string, objectKey string, body io.Reader) (*manager.UploadOutput, error) {
-	ctx, cancel := context.WithTimeout(ctx, s.timeout)
-	defer cancel()
-
-	res, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
+	return s.uploadObject(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(objectKey),
 		Body:   body,
 	})
+}
+
+func (s *S3) uploadObject(ctx context.Context, input *s3.PutObjectInput) (*manager.UploadOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if err := s.addChecksum(input); err != nil {
+		return nil, fmt.Errorf("failed to compute checksum : %w", err)
+	}
+
+	res, err := s.uploader.Upload(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload object : %v", err)
 	}
@@ -115,10 +129,16 @@ func (s *S3) UploadObject(ctx context.Context, bucketName string, objectKey stri
 }
 
 // DownloadObject gets an object from a bucket and stores it in a body.
+// When checksums are enabled, the data is verified against the checksum stored on upload and
+// a *checksum.MismatchError is returned if they differ.
 func (s *S3) DownloadObject(ctx context.Context, bucketName string, objectKey string, body io.WriterAt) error {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
+	if s.checksum != "" {
+		return s.downloadVerified(ctx, bucketName, objectKey, body)
+	}
+
 	_, err := s.downloader.Download(ctx, body, &s3.GetObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(objectKey),
@@ -130,25 +150,15 @@ func (s *S3) DownloadObject(ctx context.Context, bucketName string, objectKey st
 	return nil
 }
 
-// DeleteObjects deletes a list of objects from a bucket.
+// DeleteObjects deletes a list of objects from a bucket. On a versioned bucket, which object lock buckets always are,
+// it only adds delete markers, which object lock never refuses; use DeleteObjectVersions to delete locked versions.
 func (s *S3) DeleteObjects(ctx context.Context, bucketName string, objectKeys []string) (*s3.DeleteObjectsOutput, error) {
-	ctx, cancel := context.WithTimeout(ctx, s.timeout)
-	defer cancel()
-
 	var objectIds []types.ObjectIdentifier
 	for _, key := range objectKeys {
 		objectIds = append(objectIds, types.ObjectIdentifier{Key: aws.String(key)})
 	}
 
-	res, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
-		Bucket: aws.String(bucketName),
-		Delete: &types.Delete{Objects: objectIds},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to delete objects : %v", err)
-	}
-
-	return res, nil
+	return s.deleteObjects(ctx, bucketName, objectIds, false)
 }
 
 // ListBucketObjects lists the objects in a bucket.
@@ -199,11 +209,17 @@ func (s *S3) UploadFolder(ctx context.Context, bucketName string, folderPath str
 			return nil, fmt.Errorf("failed to open file : %v", err)
 		}
 
-		res, err := s.uploader.Upload(ctx, &s3.PutObjectInput{
+		input := &s3.PutObjectInput{
 			Bucket: aws.String(bucketName),
 			Key:    aws.String(rel),
 			Body:   file,
-		})
+		}
+		if err := s.addChecksum(input); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to compute checksum : %w", err)
+		}
+
+		res, err := s.uploader.Upload(ctx, input)
 		if err != nil {
 			file.Close()
 			return nil, fmt.Errorf("failed to upload : %v", err)
@@ -0,0 +1,93 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+// AnyAppendPosition appends a block wherever the append blob currently ends.
+const AnyAppendPosition int64 = -1
+
+// ErrAppendPositionMismatch is returned when an append blob no longer ends at the expected append position,
+// which means another writer appended to it in between.
+var ErrAppendPositionMismatch = errors.New("append position condition not met")
+
+// CreateAppendBlob creates an empty append blob. An existing blob with the same name is replaced. Options can be nil.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) CreateAppendBlob(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	options *UploadOptions) (*appendblob.CreateResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	opts := &appendblob.CreateOptions{
+		HTTPHeaders: options.httpHeaders(),
+		Metadata:    options.metadata(),
+	}
+	if options != nil {
+		opts.Tags = options.Tags
+	}
+
+	res, err := b.appendBlobClient(containerName, blobFolderPath+blobName).Create(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create append blob : %v", err)
+	}
+
+	return &res, nil
+}
+
+// AppendBlock appends body to the end of an append blob, up to 4 MiB per call. When appendPosition is not
+// AnyAppendPosition, the block is only appended if the blob is exactly that long, and ErrAppendPositionMismatch
+// is returned otherwise. The offset the block was written at is in the response's BlobAppendOffset.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) AppendBlock(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	body []byte,
+	appendPosition int64) (*appendblob.AppendBlockResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	opts := &appendblob.AppendBlockOptions{}
+	if appendPosition != AnyAppendPosition {
+		opts.AppendPositionAccessConditions = &appendblob.AppendPositionAccessConditions{AppendPosition: &appendPosition}
+	}
+
+	res, err := b.appendBlobClient(containerName, blobFolderPath+blobName).
+		AppendBlock(ctx, streaming.NopCloser(bytes.NewReader(body)), opts)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.AppendPositionConditionNotMet) {
+			return nil, ErrAppendPositionMismatch
+		}
+
+		return nil, fmt.Errorf("failed to append block : %v", err)
+	}
+
+	return &res, nil
+}
+
+// SealAppendBlob makes an append blob read-only. Further appends fail.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) SealAppendBlob(ctx context.Context, containerName string, blobFolderPath string, blobName string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	if _, err := b.appendBlobClient(containerName, blobFolderPath+blobName).Seal(ctx, nil); err != nil {
+		return fmt.Errorf("failed to seal append blob : %v", err)
+	}
+
+	return nil
+}
+
+func (b *Blob) appendBlobClient(containerName string, blobPath string) *appendblob.Client {
+	return b.client.ServiceClient().NewContainerClient(containerName).NewAppendBlobClient(blobPath)
+}
@@ -1,45 +1,124 @@
 package blob
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+	"github.com/badfan/go-toolkit/checksum"
+	"golang.org/x/sync/errgroup"
 )
 
+// BlobHierarchy is one level of a container folder: the virtual sub-folders and the blobs it directly contains.
+type BlobHierarchy struct {
+	Folders []string
+	Blobs   []*container.BlobItem
+}
+
 type Blob struct {
-	client  *azblob.Client
-	timeout time.Duration
+	client   *azblob.Client
+	timeout  time.Duration
+	checksum checksum.Algorithm
 }
 
+// CredentialType selects how NewBlob authenticates against the storage account.
+type CredentialType string
+
+const (
+	// SharedKeyCredential uses AccountName and AccountKey. It is the default.
+	SharedKeyCredential CredentialType = "shared_key"
+	// ConnectionStringCredential uses ConnectionString, which also carries the endpoint (e.g. Azurite's UseDevelopmentStorage=true).
+	ConnectionStringCredential CredentialType = "connection_string"
+	// SASCredential uses SASToken appended to the service URL.
+	SASCredential CredentialType = "sas"
+	// TokenCredential uses an Azure AD TokenCredential such as azidentity's client secret or workload identity credentials.
+	TokenCredential CredentialType = "token"
+)
+
 type BlobConfig struct {
-	AccountName string
-	AccountKey  string
+	CredentialType CredentialType
+	AccountName    string
+	AccountKey     string
+	// ConnectionString is used with ConnectionStringCredential.
+	ConnectionString string
+	// SASToken is used with SASCredential. A leading "?" is allowed.
+	SASToken string
+	// TokenCredential is used with TokenCredential.
+	TokenCredential azcore.TokenCredential
+	// ServiceURL overrides the default https://<account>.blob.core.windows.net/ endpoint, e.g. for Azurite or sovereign clouds.
+	ServiceURL string
+	// Checksum enables end-to-end integrity verification of blobs. Empty disables it.
+	Checksum checksum.Algorithm
+}
+
+// serviceURL returns the service endpoint, which is usually in the form: http(s)://<account>.blob.core.windows.net/
+func (c BlobConfig) serviceURL() string {
+	if c.ServiceURL != "" {
+		return strings.TrimSuffix(c.ServiceURL, "/") + "/"
+	}
+
+	return fmt.Sprintf("https://%s.blob.core.windows.net/", c.AccountName)
 }
 
 func NewBlob(c BlobConfig, timeout time.Duration) (*Blob, error) {
-	cred, err := azblob.NewSharedKeyCredential(c.AccountName, c.AccountKey)
+	tracing := &tracingPolicy{}
+	client, err := newClient(c, &azblob.ClientOptions{
+		ClientOptions: azcore.ClientOptions{PerCallPolicies: []policy.Policy{tracing}},
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// The service URL for blob endpoints is usually in the form: http(s)://<account>.blob.core.windows.net/
-	client, err := azblob.NewClientWithSharedKeyCredential(fmt.Sprintf("https://%s.blob.core.windows.net/", c.AccountName), cred, nil)
+	// the endpoint of a connection string is only known once the client is built
+	serviceURL, err := url.Parse(client.URL())
 	if err != nil {
 		return nil, err
 	}
+	tracing.servicePath = strings.TrimSuffix(serviceURL.Path, "/")
 
 	return &Blob{
-		client:  client,
-		timeout: timeout,
+		client:   client,
+		timeout:  timeout,
+		checksum: c.Checksum,
 	}, nil
 }
 
+func newClient(c BlobConfig, options *azblob.ClientOptions) (*azblob.Client, error) {
+	switch c.CredentialType {
+	case SharedKeyCredential, "":
+		cred, err := azblob.NewSharedKeyCredential(c.AccountName, c.AccountKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return azblob.NewClientWithSharedKeyCredential(c.serviceURL(), cred, options)
+	case ConnectionStringCredential:
+		return azblob.NewClientFromConnectionString(c.ConnectionString, options)
+	case SASCredential:
+		return azblob.NewClientWithNoCredential(c.serviceURL()+"?"+strings.TrimPrefix(c.SASToken, "?"), options)
+	case TokenCredential:
+		if c.TokenCredential == nil {
+			return nil, fmt.Errorf("token credential is required for credential type %q", c.CredentialType)
+		}
+
+		return azblob.NewClient(c.serviceURL(), c.TokenCredential, options)
+	default:
+		return nil, fmt.Errorf("unsupported credential type %q", c.CredentialType)
+	}
+}
+
 // CreateContainer creates a container with the specified name.
 func (b *Blob) CreateContainer(ctx context.Context, containerName string) (*azblob.CreateContainerResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, b.timeout)
@@ -60,10 +139,27 @@ func (b *Blob) UploadBlob(ctx context.Context,
 	blobFolderPath string,
 	blobName string,
 	body []byte) (*azblob.UploadBufferResponse, error) {
+	return b.UploadBlobWithOptions(ctx, containerName, blobFolderPath, blobName, body, nil)
+}
+
+// UploadBlobWithOptions puts body's data into a blob in a container folder and sets its HTTP headers,
+// metadata and index tags. Options can be nil.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) UploadBlobWithOptions(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	body []byte,
+	options *UploadOptions) (*azblob.UploadBufferResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, b.timeout)
 	defer cancel()
 
-	res, err := b.client.UploadBuffer(ctx, containerName, blobFolderPath+blobName, body, nil)
+	opts := options.bufferOptions()
+	if err := b.addChecksum(opts, bytes.NewReader(body)); err != nil {
+		return nil, fmt.Errorf("failed to compute checksum : %w", err)
+	}
+
+	res, err := b.client.UploadBuffer(ctx, containerName, blobFolderPath+blobName, body, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload blob : %v", err)
 	}
@@ -73,6 +169,7 @@ func (b *Blob) UploadBlob(ctx context.Context,
 
 // DownloadBlob gets a blob from a container folder and stores it in a body.
 // BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+// When checksums are enabled, a *checksum.MismatchError is returned if the body differs from the uploaded data.
 func (b *Blob) DownloadBlob(ctx context.Context, containerName string, blobFolderPath string, blobName string) ([]byte, error) {
 	ctx, cancel := context.WithTimeout(ctx, b.timeout)
 	defer cancel()
@@ -87,6 +184,10 @@ func (b *Blob) DownloadBlob(ctx context.Context, containerName string, blobFolde
 		return nil, fmt.Errorf("failed to download blob : %v", err)
 	}
 
+	if err = b.verifyChecksum(result.Metadata, bytes.NewReader(body)); err != nil {
+		return nil, err
+	}
+
 	return body, nil
 }
 
@@ -145,31 +246,178 @@ func (b *Blob) ListBlobs(ctx context.Context, containerName string, blobFolderPa
 	return res, nil
 }
 
-// UploadFolder puts local folder's files in a container folder.
+// ListBlobsHierarchy lists the blobs directly in a container folder and its virtual sub-folders, using "/" as delimiter.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) ListBlobsHierarchy(ctx context.Context, containerName string, blobFolderPath string) (*BlobHierarchy, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	pager := b.client.ServiceClient().NewContainerClient(containerName).
+		NewListBlobsHierarchyPager("/", &container.ListBlobsHierarchyOptions{Prefix: &blobFolderPath})
+	res := &BlobHierarchy{}
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list blobs from container : %v", err)
+		}
+
+		for _, prefix := range page.Segment.BlobPrefixes {
+			if prefix.Name != nil {
+				res.Folders = append(res.Folders, *prefix.Name)
+			}
+		}
+		res.Blobs = append(res.Blobs, page.Segment.BlobItems...)
+	}
+
+	return res, nil
+}
+
+// UploadFolder puts local folder's files and sub-folders in a container folder, uploading several files concurrently.
 // BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
 func (b *Blob) UploadFolder(ctx context.Context, containerName string, blobFolderPath string, localFolderPath string) ([]*azblob.UploadFileResponse, error) {
-	files, err := os.ReadDir(localFolderPath)
+	var paths []string
+	err := filepath.WalkDir(localFolderPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to read files from folder : %v", err)
 	}
 
-	var output []*azblob.UploadFileResponse
-	for _, file := range files {
-		f, err := os.Open(localFolderPath + "/" + file.Name())
-		if err != nil {
-			f.Close()
-			return nil, fmt.Errorf("failed to open file : %v", err)
+	output := make([]*azblob.UploadFileResponse, len(paths))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(defaultConcurrency)
+
+	for i, path := range paths {
+		i, path := i, path
+
+		g.Go(func() error {
+			rel, err := filepath.Rel(localFolderPath, path)
+			if err != nil {
+				return fmt.Errorf("failed to get relative : %v", err)
+			}
+
+			res, err := b.uploadFile(gctx, containerName, blobFolderPath+filepath.ToSlash(rel), path)
+			output[i] = res
+			return err
+		})
+	}
+
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// DownloadFolder gets every blob under a container folder, sub-folders included, into a local folder and
+// returns how many were downloaded. Missing local folders are created and folder marker blobs, whose name ends
+// with "/", are skipped. Nothing is downloaded if a blob is named like the folder of another one.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) DownloadFolder(ctx context.Context, containerName string, blobFolderPath string, localFolderPath string) (int, error) {
+	items, err := b.ListBlobs(ctx, containerName, blobFolderPath)
+	if err != nil {
+		return 0, err
+	}
+
+	root, err := filepath.Abs(localFolderPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get absolute : %v", err)
+	}
+
+	// every path is checked before the first download starts, so that a rejected listing writes nothing
+	paths := map[string]string{}
+	for _, item := range items {
+		if item.Name == nil {
+			continue
+		}
+		name := *item.Name
+		if strings.HasSuffix(name, "/") {
+			// a folder marker, its folder is created with the blobs under it
+			continue
 		}
 
-		res, err := b.client.UploadFile(ctx, containerName, blobFolderPath+file.Name(), f, nil)
-		if err != nil {
-			f.Close()
-			return nil, fmt.Errorf("failed to upload file : %v", err)
+		path := filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(name, blobFolderPath)))
+		if path == root {
+			// a blob named like the folder itself
+			continue
+		}
+		if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return 0, fmt.Errorf("blob %s resolves outside of folder %s", name, localFolderPath)
 		}
+		paths[name] = path
+	}
 
-		output = append(output, &res)
-		f.Close()
+	// blobs "a" and "a/b" cannot both be written, since "a" would have to be a file and a folder
+	files := make(map[string]string, len(paths))
+	for name, path := range paths {
+		files[path] = name
+	}
+	for name, path := range paths {
+		for dir := filepath.Dir(path); dir != root; dir = filepath.Dir(dir) {
+			if other, ok := files[dir]; ok {
+				return 0, fmt.Errorf("blob %s conflicts with blob %s, which would be its folder", name, other)
+			}
+		}
 	}
 
-	return output, nil
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(defaultConcurrency)
+
+	var count int
+	for name, path := range paths {
+		name, path := name, path
+		count++
+
+		g.Go(func() error {
+			return b.downloadFile(gctx, containerName, name, path)
+		})
+	}
+
+	if err = g.Wait(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (b *Blob) uploadFile(ctx context.Context, containerName string, blobPath string, localPath string) (*azblob.UploadFileResponse, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file : %v", err)
+	}
+	defer f.Close()
+
+	opts := &azblob.UploadFileOptions{}
+	if err = b.addChecksum(opts, f); err != nil {
+		return nil, fmt.Errorf("failed to compute checksum : %w", err)
+	}
+
+	res, err := b.client.UploadFile(ctx, containerName, blobPath, f, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file : %v", err)
+	}
+
+	return &res, nil
+}
+
+func (b *Blob) downloadFile(ctx context.Context, containerName string, blobPath string, localPath string) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create folder : %v", err)
+	}
+
+	f, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file : %v", err)
+	}
+	defer f.Close()
+
+	_, err = b.DownloadToWriterAt(ctx, containerName, "", blobPath, f, nil)
+	return err
 }
@@ -0,0 +1,84 @@
+package blob
+
+import (
+	"crypto/md5"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	azureblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/badfan/go-toolkit/checksum"
+)
+
+// addChecksum computes the checksum and the Content-MD5 of body in one pass, stores both on the blob
+// through the upload options and has every staged block validated with CRC64.
+// Nothing is done when checksums are disabled. Body is rewound to where it started.
+func (b *Blob) addChecksum(opts *azblob.UploadBufferOptions, body io.ReadSeeker) error {
+	if b.checksum == "" {
+		return nil
+	}
+
+	h, err := b.checksum.New()
+	if err != nil {
+		return err
+	}
+
+	start, err := body.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	contentMD5 := md5.New()
+	if _, err = io.Copy(io.MultiWriter(h, contentMD5), body); err != nil {
+		return err
+	}
+
+	if _, err = body.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+
+	if opts.HTTPHeaders == nil {
+		opts.HTTPHeaders = &azureblob.HTTPHeaders{}
+	}
+	opts.HTTPHeaders.BlobContentMD5 = contentMD5.Sum(nil)
+
+	if opts.Metadata == nil {
+		opts.Metadata = make(map[string]*string)
+	}
+	sum := checksum.Encode(h)
+	opts.Metadata[b.checksum.MetadataKey()] = &sum
+
+	opts.TransactionalValidation = azureblob.TransferValidationTypeComputeCRC64()
+
+	return nil
+}
+
+// verifyChecksum compares body with the checksum stored in the blob metadata.
+// Blobs uploaded without a checksum are not verified.
+func (b *Blob) verifyChecksum(metadata map[string]*string, body io.Reader) error {
+	if b.checksum == "" {
+		return nil
+	}
+
+	expected, ok := checksum.Lookup(derefMetadata(metadata), b.checksum)
+	if !ok {
+		return nil
+	}
+
+	actual, err := b.checksum.Sum(body)
+	if err != nil {
+		return err
+	}
+
+	return checksum.Verify(b.checksum, expected, actual)
+}
+
+func derefMetadata(metadata map[string]*string) map[string]string {
+	res := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		if v != nil {
+			res[k] = *v
+		}
+	}
+
+	return res
+}
@@ -2,9 +2,14 @@ package blob
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	azureblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/pageblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 )
 
@@ -16,4 +21,51 @@ type IBlobClient interface {
 	ListContainers(ctx context.Context) ([]*service.ContainerItem, error)
 	ListBlobs(ctx context.Context, containerName string, blobFolderPath string) ([]*container.BlobItem, error)
 	UploadFolder(ctx context.Context, containerName string, blobFolderPath string, localFolderPath string) ([]*azblob.UploadFileResponse, error)
+	UploadStream(ctx context.Context, containerName string, blobFolderPath string, blobName string, body io.Reader, options *StreamOptions) (*azblob.UploadStreamResponse, error)
+	DownloadStream(ctx context.Context, containerName string, blobFolderPath string, blobName string, offset int64, count int64) (io.ReadCloser, error)
+	DownloadToWriterAt(ctx context.Context, containerName string, blobFolderPath string, blobName string, w io.WriterAt, options *StreamOptions) (int64, error)
+	UploadBlobWithOptions(ctx context.Context, containerName string, blobFolderPath string, blobName string, body []byte, options *UploadOptions) (*azblob.UploadBufferResponse, error)
+	GetProperties(ctx context.Context, containerName string, blobFolderPath string, blobName string) (*azureblob.GetPropertiesResponse, error)
+	SetMetadata(ctx context.Context, containerName string, blobFolderPath string, blobName string, metadata map[string]string) (*azureblob.SetMetadataResponse, error)
+	GetTags(ctx context.Context, containerName string, blobFolderPath string, blobName string) (map[string]string, error)
+	SetTags(ctx context.Context, containerName string, blobFolderPath string, blobName string, tags map[string]string) (*azureblob.SetTagsResponse, error)
+	FindBlobsByTags(ctx context.Context, query string) ([]*service.FilterBlobItem, error)
+	SetAccessTier(ctx context.Context, containerName string, blobFolderPath string, blobName string, tier AccessTier) error
+	StartRehydration(ctx context.Context, containerName string, blobFolderPath string, blobName string, tier AccessTier, priority RehydratePriority) error
+	GetRehydrationStatus(ctx context.Context, containerName string, blobFolderPath string, blobName string) (*RehydrationStatus, error)
+	SetAccessTierForPrefix(ctx context.Context, containerName string, prefix string, tier AccessTier) (int, error)
+	ApplyTierRule(ctx context.Context, containerName string, rule TierRule) (int, error)
+	AcquireBlobLease(ctx context.Context, containerName string, blobFolderPath string, blobName string, duration time.Duration, proposedLeaseID string) (string, error)
+	RenewBlobLease(ctx context.Context, containerName string, blobFolderPath string, blobName string, leaseID string) error
+	ReleaseBlobLease(ctx context.Context, containerName string, blobFolderPath string, blobName string, leaseID string) error
+	BreakBlobLease(ctx context.Context, containerName string, blobFolderPath string, blobName string, breakPeriod time.Duration) error
+	AcquireContainerLease(ctx context.Context, containerName string, duration time.Duration, proposedLeaseID string) (string, error)
+	RenewContainerLease(ctx context.Context, containerName string, leaseID string) error
+	ReleaseContainerLease(ctx context.Context, containerName string, leaseID string) error
+	BreakContainerLease(ctx context.Context, containerName string, breakPeriod time.Duration) error
+	AcquireLock(ctx context.Context, containerName string, blobName string, options *LockOptions) (*Lock, error)
+	TryAcquireLock(ctx context.Context, containerName string, blobName string, options *LockOptions) (*Lock, error)
+	ListBlobsHierarchy(ctx context.Context, containerName string, blobFolderPath string) (*BlobHierarchy, error)
+	DownloadFolder(ctx context.Context, containerName string, blobFolderPath string, localFolderPath string) (int, error)
+	DeleteContainer(ctx context.Context, containerName string) (*azblob.DeleteContainerResponse, error)
+	ContainerExists(ctx context.Context, containerName string) (bool, error)
+	SetContainerMetadata(ctx context.Context, containerName string, metadata map[string]string) (*container.SetMetadataResponse, error)
+	SetContainerAccessPolicy(ctx context.Context, containerName string, publicAccess PublicAccess, policies []AccessPolicy) error
+	GetContainerAccessPolicy(ctx context.Context, containerName string) (PublicAccess, []AccessPolicy, error)
+	CreateSnapshot(ctx context.Context, containerName string, blobFolderPath string, blobName string) (string, error)
+	ListSnapshots(ctx context.Context, containerName string, blobFolderPath string, blobName string) ([]*container.BlobItem, error)
+	ListVersions(ctx context.Context, containerName string, blobFolderPath string, blobName string) ([]*container.BlobItem, error)
+	ListDeletedBlobs(ctx context.Context, containerName string, blobFolderPath string) ([]*container.BlobItem, error)
+	DownloadBlobSnapshot(ctx context.Context, containerName string, blobFolderPath string, blobName string, snapshot string) ([]byte, error)
+	DownloadBlobVersion(ctx context.Context, containerName string, blobFolderPath string, blobName string, versionID string) ([]byte, error)
+	PromoteVersion(ctx context.Context, containerName string, blobFolderPath string, blobName string, versionID string) error
+	Undelete(ctx context.Context, containerName string, blobFolderPath string, blobName string) error
+	CreateAppendBlob(ctx context.Context, containerName string, blobFolderPath string, blobName string, options *UploadOptions) (*appendblob.CreateResponse, error)
+	AppendBlock(ctx context.Context, containerName string, blobFolderPath string, blobName string, body []byte, appendPosition int64) (*appendblob.AppendBlockResponse, error)
+	SealAppendBlob(ctx context.Context, containerName string, blobFolderPath string, blobName string) error
+	CreatePageBlob(ctx context.Context, containerName string, blobFolderPath string, blobName string, size int64, options *UploadOptions) (*pageblob.CreateResponse, error)
+	WritePages(ctx context.Context, containerName string, blobFolderPath string, blobName string, offset int64, body []byte) (*pageblob.UploadPagesResponse, error)
+	ReadPages(ctx context.Context, containerName string, blobFolderPath string, blobName string, offset int64, count int64) ([]byte, error)
+	ClearPages(ctx context.Context, containerName string, blobFolderPath string, blobName string, offset int64, count int64) error
+	ListPageRanges(ctx context.Context, containerName string, blobFolderPath string, blobName string) ([]*pageblob.PageRange, error)
 }
@@ -0,0 +1,149 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+// PublicAccess is the level of anonymous read access to a container. Empty means private.
+type PublicAccess = container.PublicAccessType
+
+const (
+	PublicAccessNone      PublicAccess = ""
+	PublicAccessBlob      PublicAccess = container.PublicAccessTypeBlob
+	PublicAccessContainer PublicAccess = container.PublicAccessTypeContainer
+)
+
+// AccessPolicy is a stored access policy of a container. SAS tokens referencing its ID get its permissions
+// and validity, so they can be revoked by removing the policy.
+type AccessPolicy struct {
+	ID string
+	// Permissions is a combination of "racwdl" (read, add, create, write, delete, list).
+	Permissions string
+	// Start and Expiry are left to the SAS tokens when zero.
+	Start  time.Time
+	Expiry time.Time
+}
+
+// DeleteContainer deletes a container and all the blobs it contains.
+func (b *Blob) DeleteContainer(ctx context.Context, containerName string) (*azblob.DeleteContainerResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	res, err := b.client.DeleteContainer(ctx, containerName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete container : %v", err)
+	}
+
+	return &res, nil
+}
+
+// ContainerExists reports whether a container exists.
+func (b *Blob) ContainerExists(ctx context.Context, containerName string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	_, err := b.client.ServiceClient().NewContainerClient(containerName).GetProperties(ctx, nil)
+	if bloberror.HasCode(err, bloberror.ContainerNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get container properties : %v", err)
+	}
+
+	return true, nil
+}
+
+// SetContainerMetadata replaces the user metadata of a container.
+func (b *Blob) SetContainerMetadata(ctx context.Context, containerName string, metadata map[string]string) (*container.SetMetadataResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	res, err := b.client.ServiceClient().NewContainerClient(containerName).SetMetadata(ctx, &container.SetMetadataOptions{
+		Metadata: refMetadata(metadata),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set container metadata : %v", err)
+	}
+
+	return &res, nil
+}
+
+// SetContainerAccessPolicy replaces the public access level and the stored access policies of a container.
+func (b *Blob) SetContainerAccessPolicy(ctx context.Context, containerName string, publicAccess PublicAccess, policies []AccessPolicy) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	opts := &container.SetAccessPolicyOptions{}
+	if publicAccess != PublicAccessNone {
+		opts.Access = &publicAccess
+	}
+
+	for _, p := range policies {
+		p := p
+		policy := &container.AccessPolicy{Permission: &p.Permissions}
+		// unset times are omitted, so that the SAS tokens referencing the policy set them
+		if !p.Start.IsZero() {
+			policy.Start = &p.Start
+		}
+		if !p.Expiry.IsZero() {
+			policy.Expiry = &p.Expiry
+		}
+
+		opts.ContainerACL = append(opts.ContainerACL, &container.SignedIdentifier{
+			ID:           &p.ID,
+			AccessPolicy: policy,
+		})
+	}
+
+	_, err := b.client.ServiceClient().NewContainerClient(containerName).SetAccessPolicy(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to set container access policy : %v", err)
+	}
+
+	return nil
+}
+
+// GetContainerAccessPolicy gets the public access level and the stored access policies of a container.
+func (b *Blob) GetContainerAccessPolicy(ctx context.Context, containerName string) (PublicAccess, []AccessPolicy, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	res, err := b.client.ServiceClient().NewContainerClient(containerName).GetAccessPolicy(ctx, nil)
+	if err != nil {
+		return PublicAccessNone, nil, fmt.Errorf("failed to get container access policy : %v", err)
+	}
+
+	publicAccess := PublicAccessNone
+	if res.BlobPublicAccess != nil {
+		publicAccess = *res.BlobPublicAccess
+	}
+
+	var policies []AccessPolicy
+	for _, identifier := range res.SignedIdentifiers {
+		if identifier.ID == nil {
+			continue
+		}
+
+		policy := AccessPolicy{ID: *identifier.ID}
+		if p := identifier.AccessPolicy; p != nil {
+			if p.Permission != nil {
+				policy.Permissions = *p.Permission
+			}
+			if p.Start != nil {
+				policy.Start = *p.Start
+			}
+			if p.Expiry != nil {
+				policy.Expiry = *p.Expiry
+			}
+		}
+		policies = append(policies, policy)
+	}
+
+	return publicAccess, policies, nil
+}
@@ -0,0 +1,194 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/lease"
+)
+
+// InfiniteLease is the lease duration of a lease that is held until it is released or broken.
+const InfiniteLease time.Duration = -1
+
+// ErrLeaseAlreadyPresent is returned when acquiring a lease that is held by someone else.
+var ErrLeaseAlreadyPresent = errors.New("lease already present")
+
+// AcquireBlobLease acquires a lease on a blob and returns its ID. Duration must be between 15 and 60 seconds,
+// or InfiniteLease. An empty proposedLeaseID lets a random ID be generated.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) AcquireBlobLease(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	duration time.Duration,
+	proposedLeaseID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	leaseClient, err := b.blobLeaseClient(containerName, blobFolderPath+blobName, proposedLeaseID)
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire blob lease : %v", err)
+	}
+
+	if _, err = leaseClient.AcquireLease(ctx, leaseSeconds(duration), nil); err != nil {
+		if bloberror.HasCode(err, bloberror.LeaseAlreadyPresent) {
+			return "", ErrLeaseAlreadyPresent
+		}
+
+		return "", fmt.Errorf("failed to acquire blob lease : %v", err)
+	}
+
+	return *leaseClient.LeaseID(), nil
+}
+
+// RenewBlobLease resets the duration of a blob lease.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) RenewBlobLease(ctx context.Context, containerName string, blobFolderPath string, blobName string, leaseID string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	leaseClient, err := b.blobLeaseClient(containerName, blobFolderPath+blobName, leaseID)
+	if err != nil {
+		return fmt.Errorf("failed to renew blob lease : %v", err)
+	}
+
+	if _, err = leaseClient.RenewLease(ctx, nil); err != nil {
+		return fmt.Errorf("failed to renew blob lease : %v", err)
+	}
+
+	return nil
+}
+
+// ReleaseBlobLease releases a blob lease so that it can be acquired again immediately.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) ReleaseBlobLease(ctx context.Context, containerName string, blobFolderPath string, blobName string, leaseID string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	leaseClient, err := b.blobLeaseClient(containerName, blobFolderPath+blobName, leaseID)
+	if err != nil {
+		return fmt.Errorf("failed to release blob lease : %v", err)
+	}
+
+	if _, err = leaseClient.ReleaseLease(ctx, nil); err != nil {
+		return fmt.Errorf("failed to release blob lease : %v", err)
+	}
+
+	return nil
+}
+
+// BreakBlobLease ends a blob lease without knowing its ID, e.g. when its holder died with an infinite lease.
+// The lease stays held for at most breakPeriod, which must be between 0 and 60 seconds.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) BreakBlobLease(ctx context.Context, containerName string, blobFolderPath string, blobName string, breakPeriod time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	leaseClient, err := b.blobLeaseClient(containerName, blobFolderPath+blobName, "")
+	if err != nil {
+		return fmt.Errorf("failed to break blob lease : %v", err)
+	}
+
+	period := int32(breakPeriod / time.Second)
+	if _, err = leaseClient.BreakLease(ctx, &lease.BlobBreakOptions{BreakPeriod: &period}); err != nil {
+		return fmt.Errorf("failed to break blob lease : %v", err)
+	}
+
+	return nil
+}
+
+// AcquireContainerLease acquires a lease on a container and returns its ID. A leased container cannot be deleted
+// without the lease ID. Duration must be between 15 and 60 seconds, or InfiniteLease.
+// An empty proposedLeaseID lets a random ID be generated.
+func (b *Blob) AcquireContainerLease(ctx context.Context, containerName string, duration time.Duration, proposedLeaseID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	leaseClient, err := b.containerLeaseClient(containerName, proposedLeaseID)
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire container lease : %v", err)
+	}
+
+	if _, err = leaseClient.AcquireLease(ctx, leaseSeconds(duration), nil); err != nil {
+		if bloberror.HasCode(err, bloberror.LeaseAlreadyPresent) {
+			return "", ErrLeaseAlreadyPresent
+		}
+
+		return "", fmt.Errorf("failed to acquire container lease : %v", err)
+	}
+
+	return *leaseClient.LeaseID(), nil
+}
+
+// RenewContainerLease resets the duration of a container lease.
+func (b *Blob) RenewContainerLease(ctx context.Context, containerName string, leaseID string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	leaseClient, err := b.containerLeaseClient(containerName, leaseID)
+	if err != nil {
+		return fmt.Errorf("failed to renew container lease : %v", err)
+	}
+
+	if _, err = leaseClient.RenewLease(ctx, nil); err != nil {
+		return fmt.Errorf("failed to renew container lease : %v", err)
+	}
+
+	return nil
+}
+
+// ReleaseContainerLease releases a container lease so that it can be acquired again immediately.
+func (b *Blob) ReleaseContainerLease(ctx context.Context, containerName string, leaseID string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	leaseClient, err := b.containerLeaseClient(containerName, leaseID)
+	if err != nil {
+		return fmt.Errorf("failed to release container lease : %v", err)
+	}
+
+	if _, err = leaseClient.ReleaseLease(ctx, nil); err != nil {
+		return fmt.Errorf("failed to release container lease : %v", err)
+	}
+
+	return nil
+}
+
+// BreakContainerLease ends a container lease without knowing its ID.
+// The lease stays held for at most breakPeriod, which must be between 0 and 60 seconds.
+func (b *Blob) BreakContainerLease(ctx context.Context, containerName string, breakPeriod time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	leaseClient, err := b.containerLeaseClient(containerName, "")
+	if err != nil {
+		return fmt.Errorf("failed to break container lease : %v", err)
+	}
+
+	period := int32(breakPeriod / time.Second)
+	if _, err = leaseClient.BreakLease(ctx, &lease.ContainerBreakOptions{BreakPeriod: &period}); err != nil {
+		return fmt.Errorf("failed to break container lease : %v", err)
+	}
+
+	return nil
+}
+
+func (b *Blob) blobLeaseClient(containerName string, blobPath string, leaseID string) (*lease.BlobClient, error) {
+	return lease.NewBlobClient(b.blobClient(containerName, blobPath), &lease.BlobClientOptions{LeaseID: optionalString(leaseID)})
+}
+
+func (b *Blob) containerLeaseClient(containerName string, leaseID string) (*lease.ContainerClient, error) {
+	return lease.NewContainerClient(b.client.ServiceClient().NewContainerClient(containerName),
+		&lease.ContainerClientOptions{LeaseID: optionalString(leaseID)})
+}
+
+func leaseSeconds(duration time.Duration) int32 {
+	if duration < 0 {
+		return -1
+	}
+
+	return int32(duration / time.Second)
+}
@@ -0,0 +1,211 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	azureblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/lease"
+)
+
+const (
+	defaultLockTTL           = 30 * time.Second
+	defaultLockRetryInterval = 5 * time.Second
+)
+
+// ErrLockLost is returned by Unlock when the lease backing a lock expired or was broken before it was released.
+var ErrLockLost = errors.New("lock lost")
+
+// LockOptions configures a Lock.
+type LockOptions struct {
+	// TTL is the lease duration, between 15 and 60 seconds. The lease is renewed every TTL/3. Defaults to 30 seconds.
+	TTL time.Duration
+	// RetryInterval is the wait between acquisition attempts while the lock is held elsewhere. Defaults to 5 seconds.
+	RetryInterval time.Duration
+}
+
+func (o *LockOptions) withDefaults() LockOptions {
+	var res LockOptions
+	if o != nil {
+		res = *o
+	}
+	if res.TTL <= 0 {
+		res.TTL = defaultLockTTL
+	}
+	if res.RetryInterval <= 0 {
+		res.RetryInterval = defaultLockRetryInterval
+	}
+
+	return res
+}
+
+// Lock is a distributed mutex backed by a blob lease. It is renewed in the background until Unlock is called
+// or the context it was acquired with is done, at which point the lease is released.
+type Lock struct {
+	leaseClient *lease.BlobClient
+	ttl         time.Duration
+	timeout     time.Duration
+
+	lost   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// AcquireLock blocks until it holds the lock named blobName in a container or ctx is done.
+// The lock blob is created empty if it does not exist.
+func (b *Blob) AcquireLock(ctx context.Context, containerName string, blobName string, options *LockOptions) (*Lock, error) {
+	opts := options.withDefaults()
+
+	for {
+		lock, err := b.TryAcquireLock(ctx, containerName, blobName, &opts)
+		if !errors.Is(err, ErrLeaseAlreadyPresent) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to acquire lock : %w", ctx.Err())
+		case <-time.After(opts.RetryInterval):
+		}
+	}
+}
+
+// TryAcquireLock acquires the lock named blobName in a container, or returns ErrLeaseAlreadyPresent
+// if it is held elsewhere. The lock blob is created empty if it does not exist.
+func (b *Blob) TryAcquireLock(ctx context.Context, containerName string, blobName string, options *LockOptions) (*Lock, error) {
+	opts := options.withDefaults()
+
+	if err := b.createLockBlob(ctx, containerName, blobName); err != nil {
+		return nil, fmt.Errorf("failed to create lock blob : %v", err)
+	}
+
+	leaseClient, err := b.blobLeaseClient(containerName, blobName, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock : %v", err)
+	}
+
+	acquireCtx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	// the lease starts when the service handles the request, which is no earlier than it is sent
+	acquired := time.Now()
+	if _, err = leaseClient.AcquireLease(acquireCtx, leaseSeconds(opts.TTL), nil); err != nil {
+		if bloberror.HasCode(err, bloberror.LeaseAlreadyPresent) {
+			return nil, ErrLeaseAlreadyPresent
+		}
+
+		return nil, fmt.Errorf("failed to acquire lock : %v", err)
+	}
+
+	lockCtx, lockCancel := context.WithCancel(ctx)
+	lock := &Lock{
+		leaseClient: leaseClient,
+		ttl:         opts.TTL,
+		timeout:     b.timeout,
+		lost:        make(chan struct{}),
+		cancel:      lockCancel,
+		done:        make(chan struct{}),
+	}
+	go lock.keepAlive(lockCtx, acquired)
+
+	return lock, nil
+}
+
+// Lost is closed when the lease could not be renewed for two thirds of its TTL, before it can expire.
+// Work guarded by the lock must stop then, because another instance may hold it once the lease expired.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Unlock stops renewing the lease and releases it. It returns ErrLockLost if the lock had been lost.
+func (l *Lock) Unlock() error {
+	l.cancel()
+	<-l.done
+
+	return l.err
+}
+
+// keepAlive renews the lease acquired at the given time. Renewals are timed from when their request was sent,
+// so that a slow response cannot push the loss of the lock past the expiry of the lease.
+func (l *Lock) keepAlive(ctx context.Context, acquired time.Time) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	// the lock is given up a third of the TTL before the lease can expire, so that Lost is closed
+	// before another instance can acquire it
+	lostAfter := l.ttl * 2 / 3
+	renewed := acquired
+	for {
+		select {
+		case <-ctx.Done():
+			// the acquiring context may be done already, so the release gets its own
+			releaseCtx, cancel := context.WithTimeout(context.Background(), l.timeout)
+			defer cancel()
+
+			if _, err := l.leaseClient.ReleaseLease(releaseCtx, nil); err != nil {
+				l.err = fmt.Errorf("failed to release lock : %v", err)
+			}
+			return
+		case <-ticker.C:
+			// a renewal must not outlive the time the lock is still held for
+			timeout := l.timeout
+			if remaining := time.Until(renewed.Add(lostAfter)); remaining < timeout {
+				timeout = remaining
+			}
+
+			sent := time.Now()
+			var err error
+			if timeout > 0 {
+				renewCtx, cancel := context.WithTimeout(ctx, timeout)
+				_, err = l.leaseClient.RenewLease(renewCtx, nil)
+				cancel()
+			} else {
+				err = context.DeadlineExceeded
+			}
+
+			if err == nil {
+				renewed = sent
+				continue
+			}
+			if ctx.Err() != nil {
+				continue
+			}
+
+			// transient failures are retried on the next tick for as long as the lock is still held
+			if time.Since(renewed) >= lostAfter || bloberror.HasCode(err,
+				bloberror.LeaseIDMismatchWithLeaseOperation,
+				bloberror.LeaseNotPresentWithLeaseOperation,
+				bloberror.LeaseIsBrokenAndCannotBeRenewed,
+				bloberror.LeaseLost) {
+				l.err = ErrLockLost
+				close(l.lost)
+				return
+			}
+		}
+	}
+}
+
+func (b *Blob) createLockBlob(ctx context.Context, containerName string, blobName string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	etagAny := azcore.ETagAny
+	_, err := b.client.UploadBuffer(ctx, containerName, blobName, nil, &azblob.UploadBufferOptions{
+		AccessConditions: &azureblob.AccessConditions{
+			ModifiedAccessConditions: &azureblob.ModifiedAccessConditions{IfNoneMatch: &etagAny},
+		},
+	})
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobAlreadyExists, bloberror.ConditionNotMet, bloberror.LeaseIDMissing) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	azureblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/pageblob"
+)
+
+// PageSize is the alignment of page blob sizes, offsets and write lengths.
+const PageSize = 512
+
+// ErrUnalignedPage is returned when a page blob size, offset or length is not a multiple of PageSize.
+var ErrUnalignedPage = errors.New("page blob range is not aligned to 512 bytes")
+
+// CreatePageBlob creates a page blob of size bytes, filled with zeros. Size must be a multiple of PageSize.
+// An existing blob with the same name is replaced. Options can be nil.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) CreatePageBlob(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	size int64,
+	options *UploadOptions) (*pageblob.CreateResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	if size%PageSize != 0 {
+		return nil, ErrUnalignedPage
+	}
+
+	opts := &pageblob.CreateOptions{
+		HTTPHeaders: options.httpHeaders(),
+		Metadata:    options.metadata(),
+	}
+	if options != nil {
+		opts.Tags = options.Tags
+	}
+
+	res, err := b.pageBlobClient(containerName, blobFolderPath+blobName).Create(ctx, size, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create page blob : %v", err)
+	}
+
+	return &res, nil
+}
+
+// WritePages writes body at offset in a page blob, up to 4 MiB per call. Offset and the length of body
+// must be multiples of PageSize.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) WritePages(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	offset int64,
+	body []byte) (*pageblob.UploadPagesResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	if offset%PageSize != 0 || len(body)%PageSize != 0 {
+		return nil, ErrUnalignedPage
+	}
+
+	res, err := b.pageBlobClient(containerName, blobFolderPath+blobName).UploadPages(ctx,
+		streaming.NopCloser(bytes.NewReader(body)),
+		azureblob.HTTPRange{Offset: offset, Count: int64(len(body))},
+		nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write pages : %v", err)
+	}
+
+	return &res, nil
+}
+
+// ReadPages reads count bytes of a page blob starting at offset. Pages never written read as zeros.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) ReadPages(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	offset int64,
+	count int64) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	res, err := b.pageBlobClient(containerName, blobFolderPath+blobName).DownloadStream(ctx, &azureblob.DownloadStreamOptions{
+		Range: azureblob.HTTPRange{Offset: offset, Count: count},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pages : %v", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pages : %v", err)
+	}
+
+	return body, nil
+}
+
+// ClearPages releases count bytes of a page blob starting at offset, which then read as zeros.
+// Offset and count must be multiples of PageSize.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) ClearPages(ctx context.Context, containerName string, blobFolderPath string, blobName string, offset int64, count int64) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	if offset%PageSize != 0 || count%PageSize != 0 {
+		return ErrUnalignedPage
+	}
+
+	_, err := b.pageBlobClient(containerName, blobFolderPath+blobName).
+		ClearPages(ctx, azureblob.HTTPRange{Offset: offset, Count: count}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to clear pages : %v", err)
+	}
+
+	return nil
+}
+
+// ListPageRanges lists the ranges of a page blob that hold data. Start and End are inclusive byte offsets.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) ListPageRanges(ctx context.Context, containerName string, blobFolderPath string, blobName string) ([]*pageblob.PageRange, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	pager := b.pageBlobClient(containerName, blobFolderPath+blobName).NewGetPageRangesPager(nil)
+	var res []*pageblob.PageRange
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list page ranges : %v", err)
+		}
+
+		res = append(res, page.PageRange...)
+	}
+
+	return res, nil
+}
+
+func (b *Blob) pageBlobClient(containerName string, blobPath string) *pageblob.Client {
+	return b.client.ServiceClient().NewContainerClient(containerName).NewPageBlobClient(blobPath)
+}
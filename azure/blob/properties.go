@@ -0,0 +1,197 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	azureblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+)
+
+// UploadOptions sets the HTTP headers, user metadata and index tags stored with an uploaded blob.
+type UploadOptions struct {
+	ContentType        string
+	ContentEncoding    string
+	ContentLanguage    string
+	ContentDisposition string
+	CacheControl       string
+	Metadata           map[string]string
+	// Tags are indexed by the service and can be queried with FindBlobsByTags.
+	Tags map[string]string
+	// AccessTier is the tier the blob is stored in. Empty uses the account default.
+	AccessTier AccessTier
+}
+
+func (o *UploadOptions) bufferOptions() *azblob.UploadBufferOptions {
+	if o == nil {
+		return &azblob.UploadBufferOptions{}
+	}
+
+	return &azblob.UploadBufferOptions{
+		HTTPHeaders: o.httpHeaders(),
+		Metadata:    o.metadata(),
+		Tags:        o.Tags,
+		AccessTier:  o.accessTier(),
+	}
+}
+
+func (o *UploadOptions) accessTier() *AccessTier {
+	if o == nil || o.AccessTier == "" {
+		return nil
+	}
+
+	return &o.AccessTier
+}
+
+func (o *UploadOptions) httpHeaders() *azureblob.HTTPHeaders {
+	if o == nil {
+		return nil
+	}
+
+	return &azureblob.HTTPHeaders{
+		BlobContentType:        optionalString(o.ContentType),
+		BlobContentEncoding:    optionalString(o.ContentEncoding),
+		BlobContentLanguage:    optionalString(o.ContentLanguage),
+		BlobContentDisposition: optionalString(o.ContentDisposition),
+		BlobCacheControl:       optionalString(o.CacheControl),
+	}
+}
+
+func (o *UploadOptions) metadata() map[string]*string {
+	if o == nil || o.Metadata == nil {
+		return nil
+	}
+
+	return refMetadata(o.Metadata)
+}
+
+// GetProperties gets the HTTP headers, user metadata and system properties of a blob without downloading it.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) GetProperties(ctx context.Context, containerName string, blobFolderPath string, blobName string) (*azureblob.GetPropertiesResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	res, err := b.blobClient(containerName, blobFolderPath+blobName).GetProperties(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob properties : %v", err)
+	}
+
+	return &res, nil
+}
+
+// SetMetadata replaces the user metadata of a blob. The checksum stored on upload is kept.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) SetMetadata(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	metadata map[string]string) (*azureblob.SetMetadataResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	blobClient := b.blobClient(containerName, blobFolderPath+blobName)
+	values := refMetadata(metadata)
+
+	if b.checksum != "" {
+		props, err := blobClient.GetProperties(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set blob metadata : %v", err)
+		}
+
+		for k, v := range props.Metadata {
+			if strings.EqualFold(k, b.checksum.MetadataKey()) {
+				values[b.checksum.MetadataKey()] = v
+			}
+		}
+	}
+
+	res, err := blobClient.SetMetadata(ctx, values, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set blob metadata : %v", err)
+	}
+
+	return &res, nil
+}
+
+// GetTags gets the index tags of a blob.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) GetTags(ctx context.Context, containerName string, blobFolderPath string, blobName string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	res, err := b.blobClient(containerName, blobFolderPath+blobName).GetTags(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob tags : %v", err)
+	}
+
+	tags := make(map[string]string, len(res.BlobTagSet))
+	for _, tag := range res.BlobTagSet {
+		if tag.Key != nil && tag.Value != nil {
+			tags[*tag.Key] = *tag.Value
+		}
+	}
+
+	return tags, nil
+}
+
+// SetTags replaces the index tags of a blob.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) SetTags(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	tags map[string]string) (*azureblob.SetTagsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	res, err := b.blobClient(containerName, blobFolderPath+blobName).SetTags(ctx, tags, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set blob tags : %v", err)
+	}
+
+	return &res, nil
+}
+
+// FindBlobsByTags lists the blobs of the account whose index tags match query,
+// e.g. "tenant = 'acme' AND status = 'archived'". A "@container = 'name'" clause restricts the search to a container.
+func (b *Blob) FindBlobsByTags(ctx context.Context, query string) ([]*service.FilterBlobItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	var res []*service.FilterBlobItem
+	opts := &service.FilterBlobsOptions{}
+
+	for {
+		page, err := b.client.ServiceClient().FilterBlobs(ctx, query, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find blobs by tags : %v", err)
+		}
+
+		res = append(res, page.Blobs...)
+
+		if page.NextMarker == nil || *page.NextMarker == "" {
+			return res, nil
+		}
+		opts.Marker = page.NextMarker
+	}
+}
+
+func refMetadata(metadata map[string]string) map[string]*string {
+	res := make(map[string]*string, len(metadata))
+	for k, v := range metadata {
+		v := v
+		res[k] = &v
+	}
+
+	return res
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
@@ -0,0 +1,303 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	azureblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/badfan/go-toolkit/checksum"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	defaultBlockSize   = 4 * 1024 * 1024
+	defaultConcurrency = 5
+	maxReadRetries     = 3
+)
+
+// StreamOptions configures how streaming transfers are split into blocks.
+type StreamOptions struct {
+	// BlockSize is the size of each staged or downloaded block. Defaults to 4 MiB.
+	BlockSize int64
+	// Concurrency is the number of blocks transferred in parallel. Defaults to 5.
+	Concurrency int
+	// Upload sets the properties of blobs uploaded with UploadStream. It is ignored by downloads.
+	Upload *UploadOptions
+}
+
+func (o *StreamOptions) withDefaults() StreamOptions {
+	var res StreamOptions
+	if o != nil {
+		res = *o
+	}
+	if res.BlockSize <= 0 {
+		res.BlockSize = defaultBlockSize
+	}
+	if res.Concurrency <= 0 {
+		res.Concurrency = defaultConcurrency
+	}
+
+	return res
+}
+
+// UploadStream reads body to the end and uploads it as a block blob, staging blocks concurrently,
+// so memory use is bounded by BlockSize * Concurrency. Options can be nil. The upload runs until ctx is done
+// rather than within the client timeout, since large bodies take longer. When checksums are enabled, body is
+// hashed while it is read and the checksum is committed together with the block list, so that the blob never
+// exists without it.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) UploadStream(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	body io.Reader,
+	options *StreamOptions) (*azblob.UploadStreamResponse, error) {
+	opts := options.withDefaults()
+	blockBlobClient := b.blockBlobClient(containerName, blobFolderPath+blobName)
+
+	var h, contentMD5 hash.Hash
+	var stageOptions *blockblob.StageBlockOptions
+	if b.checksum != "" {
+		var err error
+		if h, err = b.checksum.New(); err != nil {
+			return nil, fmt.Errorf("failed to compute checksum : %w", err)
+		}
+		contentMD5 = md5.New()
+		body = io.TeeReader(body, io.MultiWriter(h, contentMD5))
+		stageOptions = &blockblob.StageBlockOptions{
+			TransactionalValidation: azureblob.TransferValidationTypeComputeCRC64(),
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	// buffers are reused once their block is staged, which bounds memory use
+	buffers := make(chan []byte, opts.Concurrency)
+	for i := 0; i < opts.Concurrency; i++ {
+		buffers <- make([]byte, opts.BlockSize)
+	}
+
+	var blockIDs []string
+	for {
+		var buf []byte
+		select {
+		case buf = <-buffers:
+		case <-gctx.Done():
+		}
+		if buf == nil {
+			break
+		}
+
+		n, err := io.ReadFull(body, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			g.Go(func() error {
+				return err
+			})
+			break
+		}
+		if n == 0 {
+			break
+		}
+
+		// block IDs must all have the same length
+		blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%016d", len(blockIDs))))
+		blockIDs = append(blockIDs, blockID)
+		g.Go(func() error {
+			defer func() { buffers <- buf }()
+
+			_, err := blockBlobClient.StageBlock(gctx, blockID, streaming.NopCloser(bytes.NewReader(buf[:n])), stageOptions)
+			return err
+		})
+
+		if n < len(buf) {
+			break
+		}
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, fmt.Errorf("failed to upload stream : %v", err)
+	}
+
+	commitOptions := &blockblob.CommitBlockListOptions{
+		HTTPHeaders: opts.Upload.httpHeaders(),
+		Metadata:    opts.Upload.metadata(),
+		Tier:        opts.Upload.accessTier(),
+	}
+	if opts.Upload != nil {
+		commitOptions.Tags = opts.Upload.Tags
+	}
+	if h != nil {
+		if commitOptions.HTTPHeaders == nil {
+			commitOptions.HTTPHeaders = &azureblob.HTTPHeaders{}
+		}
+		commitOptions.HTTPHeaders.BlobContentMD5 = contentMD5.Sum(nil)
+
+		if commitOptions.Metadata == nil {
+			commitOptions.Metadata = make(map[string]*string)
+		}
+		sum := checksum.Encode(h)
+		commitOptions.Metadata[b.checksum.MetadataKey()] = &sum
+	}
+
+	res, err := blockBlobClient.CommitBlockList(ctx, blockIDs, commitOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload stream : %v", err)
+	}
+
+	return &res, nil
+}
+
+// DownloadStream returns a reader over count bytes of a blob starting at offset. A zero count reads up to
+// the end of the blob. The reader retries interrupted reads and must be closed by the caller.
+// When checksums are enabled and the whole blob is read, the final Read returns a *checksum.MismatchError
+// instead of io.EOF if the data differs from the uploaded one.
+// The download is not bound to the client timeout: the reader keeps using ctx until it is closed.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) DownloadStream(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	offset int64,
+	count int64) (io.ReadCloser, error) {
+	res, err := b.client.DownloadStream(ctx, containerName, blobFolderPath+blobName, &azblob.DownloadStreamOptions{
+		Range: azblob.HTTPRange{Offset: offset, Count: count},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download stream : %v", err)
+	}
+
+	body := res.NewRetryReader(ctx, &azblob.RetryReaderOptions{MaxRetries: maxReadRetries})
+
+	if b.checksum == "" || offset != 0 || count != 0 {
+		return body, nil
+	}
+
+	expected, ok := checksum.Lookup(derefMetadata(res.Metadata), b.checksum)
+	if !ok {
+		return body, nil
+	}
+
+	h, err := b.checksum.New()
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	return &verifyingReader{ReadCloser: body, h: h, algorithm: b.checksum, expected: expected}, nil
+}
+
+// DownloadToWriterAt downloads a blob into w using concurrent ranged requests, like manager.Downloader
+// does for S3, and returns the number of bytes written. Options can be nil. When checksums are enabled,
+// blocks are downloaded one at a time so that they can be verified. Only ctx bounds how long it runs.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) DownloadToWriterAt(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	w io.WriterAt,
+	options *StreamOptions) (int64, error) {
+	opts := options.withDefaults()
+	blobClient := b.blobClient(containerName, blobFolderPath+blobName)
+
+	props, err := blobClient.GetProperties(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get blob properties : %v", err)
+	}
+
+	var size int64
+	if props.ContentLength != nil {
+		size = *props.ContentLength
+	}
+
+	var h hash.Hash
+	var expected string
+	if b.checksum != "" {
+		var ok bool
+		if expected, ok = checksum.Lookup(derefMetadata(props.Metadata), b.checksum); ok {
+			if h, err = b.checksum.New(); err != nil {
+				return 0, err
+			}
+			w = checksum.NewHashingWriterAt(w, h)
+			opts.Concurrency = 1
+		}
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(opts.Concurrency)
+
+	for offset := int64(0); offset < size; offset += opts.BlockSize {
+		offset := offset
+		count := opts.BlockSize
+		if offset+count > size {
+			count = size - offset
+		}
+
+		g.Go(func() error {
+			res, err := blobClient.DownloadStream(gctx, &azureblob.DownloadStreamOptions{
+				Range: azureblob.HTTPRange{Offset: offset, Count: count},
+				// every block must come from the blob version whose size was read
+				AccessConditions: &azureblob.AccessConditions{
+					ModifiedAccessConditions: &azureblob.ModifiedAccessConditions{IfMatch: props.ETag},
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			body := res.NewRetryReader(gctx, &azureblob.RetryReaderOptions{MaxRetries: maxReadRetries})
+			defer body.Close()
+
+			_, err = io.Copy(io.NewOffsetWriter(w, offset), body)
+			return err
+		})
+	}
+
+	if err = g.Wait(); err != nil {
+		return 0, fmt.Errorf("failed to download blob : %v", err)
+	}
+
+	if h != nil {
+		if err = checksum.Verify(b.checksum, expected, checksum.Encode(h)); err != nil {
+			return 0, err
+		}
+	}
+
+	return size, nil
+}
+
+func (b *Blob) blobClient(containerName string, blobPath string) *azureblob.Client {
+	return b.client.ServiceClient().NewContainerClient(containerName).NewBlobClient(blobPath)
+}
+
+func (b *Blob) blockBlobClient(containerName string, blobPath string) *blockblob.Client {
+	return b.client.ServiceClient().NewContainerClient(containerName).NewBlockBlobClient(blobPath)
+}
+
+// verifyingReader hashes everything read through it and checks the checksum once the end is reached.
+type verifyingReader struct {
+	io.ReadCloser
+	h         hash.Hash
+	algorithm checksum.Algorithm
+	expected  string
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.h.Write(p[:n])
+
+	if err == io.EOF {
+		if verr := checksum.Verify(r.algorithm, r.expected, checksum.Encode(r.h)); verr != nil {
+			return n, verr
+		}
+	}
+
+	return n, err
+}
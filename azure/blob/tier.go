@@ -0,0 +1,212 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	azureblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"golang.org/x/sync/errgroup"
+)
+
+// AccessTier is the storage tier of a block blob. Cooler tiers are cheaper to store and more expensive to read.
+type AccessTier = azureblob.AccessTier
+
+const (
+	TierHot     AccessTier = azureblob.AccessTierHot
+	TierCool    AccessTier = azureblob.AccessTierCool
+	TierArchive AccessTier = azureblob.AccessTierArchive
+)
+
+var ErrUnknownAccessTier = errors.New("unknown access tier")
+
+// tierOrder ranks tiers from the hottest to the coolest. The Cold tier is missing, since it needs a newer
+// service version than the SDK sends.
+var tierOrder = map[AccessTier]int{
+	TierHot:     0,
+	TierCool:    1,
+	TierArchive: 2,
+}
+
+// RehydratePriority is the priority of a rehydration from the Archive tier. High priority may complete
+// in under an hour for small blobs, Standard may take up to 15 hours.
+type RehydratePriority = azureblob.RehydratePriority
+
+const (
+	RehydrateStandard RehydratePriority = azureblob.RehydratePriorityStandard
+	RehydrateHigh     RehydratePriority = azureblob.RehydratePriorityHigh
+)
+
+// RehydrationStatus describes the tier of a blob and its pending rehydration, if any.
+type RehydrationStatus struct {
+	Tier AccessTier
+	// Pending is true while the blob is being rehydrated out of the Archive tier.
+	Pending bool
+	// TargetTier is the tier the blob is being rehydrated to.
+	TargetTier AccessTier
+	Priority   RehydratePriority
+}
+
+// TierRule moves the blobs under Prefix that were last modified more than OlderThan ago to Tier,
+// like a lifecycle management rule. Blobs already in Tier or in a cooler one are left untouched.
+type TierRule struct {
+	Prefix    string
+	OlderThan time.Duration
+	Tier      AccessTier
+}
+
+// SetAccessTier moves a blob to another access tier. Moving a blob out of the Archive tier starts a
+// standard priority rehydration; use StartRehydration to choose the priority.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) SetAccessTier(ctx context.Context, containerName string, blobFolderPath string, blobName string, tier AccessTier) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	_, err := b.blobClient(containerName, blobFolderPath+blobName).SetTier(ctx, tier, nil)
+	if err != nil {
+		return fmt.Errorf("failed to set access tier : %v", err)
+	}
+
+	return nil
+}
+
+// StartRehydration starts moving an archived blob to an online tier with the given priority.
+// The blob stays unreadable until GetRehydrationStatus reports it is no longer pending.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) StartRehydration(ctx context.Context,
+	containerName string,
+	blobFolderPath string,
+	blobName string,
+	tier AccessTier,
+	priority RehydratePriority) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	_, err := b.blobClient(containerName, blobFolderPath+blobName).SetTier(ctx, tier, &azureblob.SetTierOptions{
+		RehydratePriority: &priority,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start rehydration : %v", err)
+	}
+
+	return nil
+}
+
+// GetRehydrationStatus gets the current tier of a blob and the state of its rehydration.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) GetRehydrationStatus(ctx context.Context, containerName string, blobFolderPath string, blobName string) (*RehydrationStatus, error) {
+	props, err := b.GetProperties(ctx, containerName, blobFolderPath, blobName)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &RehydrationStatus{}
+	if props.AccessTier != nil {
+		status.Tier = AccessTier(*props.AccessTier)
+	}
+	if props.RehydratePriority != nil {
+		status.Priority = RehydratePriority(*props.RehydratePriority)
+	}
+	if props.ArchiveStatus != nil {
+		status.Pending = true
+		switch azureblob.ArchiveStatus(*props.ArchiveStatus) {
+		case azureblob.ArchiveStatusRehydratePendingToHot:
+			status.TargetTier = TierHot
+		case azureblob.ArchiveStatusRehydratePendingToCool:
+			status.TargetTier = TierCool
+		}
+	}
+
+	return status, nil
+}
+
+// SetAccessTierForPrefix moves every block blob under a prefix to another access tier and returns how many were
+// moved. On error, the count covers the blobs moved until then.
+func (b *Blob) SetAccessTierForPrefix(ctx context.Context, containerName string, prefix string, tier AccessTier) (int, error) {
+	if err := checkTier(tier); err != nil {
+		return 0, err
+	}
+
+	items, err := b.ListBlobs(ctx, containerName, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	return b.setTiers(ctx, containerName, items, tier)
+}
+
+// ApplyTierRule moves the blobs matching a rule to a cooler tier and returns how many were moved.
+// It is meant to be run periodically, e.g. from a scheduled job. On error, the count covers the blobs moved
+// until then.
+func (b *Blob) ApplyTierRule(ctx context.Context, containerName string, rule TierRule) (int, error) {
+	if err := checkTier(rule.Tier); err != nil {
+		return 0, err
+	}
+
+	items, err := b.ListBlobs(ctx, containerName, rule.Prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-rule.OlderThan)
+
+	var matching []*container.BlobItem
+	for _, item := range items {
+		if item.Properties == nil || item.Properties.LastModified == nil || item.Properties.LastModified.After(cutoff) {
+			continue
+		}
+
+		// blobs in a tier without a rank, e.g. Cold, are left alone rather than possibly moved to a warmer one
+		if item.Properties.AccessTier != nil {
+			current, ok := tierOrder[AccessTier(*item.Properties.AccessTier)]
+			if !ok || current >= tierOrder[rule.Tier] {
+				continue
+			}
+		}
+
+		matching = append(matching, item)
+	}
+
+	return b.setTiers(ctx, containerName, matching, rule.Tier)
+}
+
+// setTiers moves the block blobs among items to a tier. Other blob types have no access tier, and blobs already
+// in the tier are skipped.
+func (b *Blob) setTiers(ctx context.Context, containerName string, items []*container.BlobItem, tier AccessTier) (int, error) {
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(defaultConcurrency)
+
+	var count atomic.Int64
+	for _, item := range items {
+		if item.Name == nil || item.Properties == nil || item.Properties.AccessTier == nil ||
+			item.Properties.BlobType == nil || *item.Properties.BlobType != azureblob.BlobTypeBlockBlob ||
+			AccessTier(*item.Properties.AccessTier) == tier {
+			continue
+		}
+		name := *item.Name
+
+		g.Go(func() error {
+			if err := b.SetAccessTier(gctx, containerName, "", name, tier); err != nil {
+				return err
+			}
+			count.Add(1)
+
+			return nil
+		})
+	}
+
+	err := g.Wait()
+
+	return int(count.Load()), err
+}
+
+func checkTier(tier AccessTier) error {
+	if _, ok := tierOrder[tier]; !ok {
+		return fmt.Errorf("%w : %q", ErrUnknownAccessTier, tier)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package blob
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/badfan/go-toolkit/tracer"
+)
+
+// tracingPolicy wraps every request sent by the azblob client in a client span, the way otelaws does for S3.
+type tracingPolicy struct {
+	// servicePath is the path of the service URL, e.g. "/devstoreaccount1" for Azurite, stripped before
+	// the container and blob names are read from the request path.
+	servicePath string
+}
+
+func (p *tracingPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+	containerName, blobName := p.splitPath(raw.URL.Path)
+
+	tags := map[string]string{
+		"rpc.system":       "azure_blob",
+		"http.method":      raw.Method,
+		"azure.container":  containerName,
+		"azure.blob":       blobName,
+		"azure.account":    raw.URL.Host,
+		"http.request_len": strconv.FormatInt(raw.ContentLength, 10),
+	}
+
+	ctx, span := tracer.NewSpan(raw.Context(), operationName(raw), tracer.ClientSpan{Tags: tags})
+	defer span.End()
+
+	res, err := req.Clone(ctx).Next()
+	if err != nil {
+		tracer.AddSpanError(span, err)
+		tracer.FailSpan(span, err.Error())
+		return res, err
+	}
+
+	resTags := map[string]string{"http.status_code": strconv.Itoa(res.StatusCode)}
+	if res.ContentLength >= 0 {
+		resTags["http.response_len"] = strconv.FormatInt(res.ContentLength, 10)
+	}
+	if code := res.Header.Get("x-ms-error-code"); code != "" {
+		resTags["azure.error_code"] = code
+	}
+	tracer.AddSpanTags(span, resTags)
+
+	if res.StatusCode >= http.StatusBadRequest {
+		// the error reads the body once and caches it, so the client can still parse it
+		tracer.AddSpanError(span, runtime.NewResponseError(res))
+		tracer.FailSpan(span, res.Status)
+	}
+
+	return res, nil
+}
+
+func (p *tracingPolicy) splitPath(path string) (string, string) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, p.servicePath), "/")
+	containerName, blobName, _ := strings.Cut(path, "/")
+
+	return containerName, blobName
+}
+
+// operationName names a span after the HTTP method and the "comp" query parameter that identifies
+// the storage operation, e.g. "azblob PUT block" or "azblob GET list".
+func operationName(req *http.Request) string {
+	name := "azblob " + req.Method
+	if comp := req.URL.Query().Get("comp"); comp != "" {
+		name += " " + comp
+	}
+
+	return name
+}
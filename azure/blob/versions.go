@@ -0,0 +1,198 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	azureblob "github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+const copyPollInterval = time.Second
+
+// CreateSnapshot creates a read-only snapshot of a blob and returns its snapshot ID.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) CreateSnapshot(ctx context.Context, containerName string, blobFolderPath string, blobName string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	res, err := b.blobClient(containerName, blobFolderPath+blobName).CreateSnapshot(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot : %v", err)
+	}
+
+	if res.Snapshot == nil {
+		return "", fmt.Errorf("failed to create snapshot : no snapshot id returned")
+	}
+
+	return *res.Snapshot, nil
+}
+
+// ListSnapshots lists the snapshots of a blob.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) ListSnapshots(ctx context.Context, containerName string, blobFolderPath string, blobName string) ([]*container.BlobItem, error) {
+	items, err := b.listBlobsIncluding(ctx, containerName, blobFolderPath+blobName, container.ListBlobsInclude{Snapshots: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots : %v", err)
+	}
+
+	var res []*container.BlobItem
+	for _, item := range items {
+		if item.Name != nil && *item.Name == blobFolderPath+blobName && item.Snapshot != nil && *item.Snapshot != "" {
+			res = append(res, item)
+		}
+	}
+
+	return res, nil
+}
+
+// ListVersions lists the versions of a blob, the current one included. Versioning must be enabled on the account.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) ListVersions(ctx context.Context, containerName string, blobFolderPath string, blobName string) ([]*container.BlobItem, error) {
+	items, err := b.listBlobsIncluding(ctx, containerName, blobFolderPath+blobName, container.ListBlobsInclude{Versions: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versions : %v", err)
+	}
+
+	var res []*container.BlobItem
+	for _, item := range items {
+		if item.Name != nil && *item.Name == blobFolderPath+blobName && item.VersionID != nil {
+			res = append(res, item)
+		}
+	}
+
+	return res, nil
+}
+
+// ListDeletedBlobs lists the soft-deleted blobs in a container folder that can still be restored with Undelete.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) ListDeletedBlobs(ctx context.Context, containerName string, blobFolderPath string) ([]*container.BlobItem, error) {
+	items, err := b.listBlobsIncluding(ctx, containerName, blobFolderPath, container.ListBlobsInclude{Deleted: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleted blobs : %v", err)
+	}
+
+	var res []*container.BlobItem
+	for _, item := range items {
+		if item.Deleted != nil && *item.Deleted {
+			res = append(res, item)
+		}
+	}
+
+	return res, nil
+}
+
+// DownloadBlobSnapshot gets a snapshot of a blob.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) DownloadBlobSnapshot(ctx context.Context, containerName string, blobFolderPath string, blobName string, snapshot string) ([]byte, error) {
+	blobClient, err := b.blobClient(containerName, blobFolderPath+blobName).WithSnapshot(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob snapshot : %v", err)
+	}
+
+	return b.downloadAll(ctx, blobClient)
+}
+
+// DownloadBlobVersion gets a specific version of a blob.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) DownloadBlobVersion(ctx context.Context, containerName string, blobFolderPath string, blobName string, versionID string) ([]byte, error) {
+	blobClient, err := b.blobClient(containerName, blobFolderPath+blobName).WithVersionID(versionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob version : %v", err)
+	}
+
+	return b.downloadAll(ctx, blobClient)
+}
+
+// PromoteVersion makes an earlier version the current version of a blob by copying it over the blob,
+// and waits for the copy to complete. The version being replaced is kept as a previous version.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) PromoteVersion(ctx context.Context, containerName string, blobFolderPath string, blobName string, versionID string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	blobClient := b.blobClient(containerName, blobFolderPath+blobName)
+	versionClient, err := blobClient.WithVersionID(versionID)
+	if err != nil {
+		return fmt.Errorf("failed to promote version : %v", err)
+	}
+
+	res, err := blobClient.StartCopyFromURL(ctx, versionClient.URL(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to promote version : %v", err)
+	}
+
+	status := res.CopyStatus
+	for status != nil && *status == azureblob.CopyStatusTypePending {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to promote version : %v", ctx.Err())
+		case <-time.After(copyPollInterval):
+		}
+
+		props, err := blobClient.GetProperties(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to promote version : %v", err)
+		}
+		status = props.CopyStatus
+	}
+
+	if status != nil && *status != azureblob.CopyStatusTypeSuccess {
+		return fmt.Errorf("failed to promote version : copy %s", *status)
+	}
+
+	return nil
+}
+
+// Undelete restores a soft-deleted blob and its soft-deleted snapshots.
+// BlobFolderPath must be of the following format: "exampleFolder1/exampleFolder2/". BlobFolderPath can be an empty string
+func (b *Blob) Undelete(ctx context.Context, containerName string, blobFolderPath string, blobName string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	if _, err := b.blobClient(containerName, blobFolderPath+blobName).Undelete(ctx, nil); err != nil {
+		return fmt.Errorf("failed to undelete blob : %v", err)
+	}
+
+	return nil
+}
+
+func (b *Blob) listBlobsIncluding(ctx context.Context, containerName string, prefix string, include container.ListBlobsInclude) ([]*container.BlobItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	pager := b.client.NewListBlobsFlatPager(containerName, &azblob.ListBlobsFlatOptions{Prefix: &prefix, Include: include})
+	var res []*container.BlobItem
+
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, page.Segment.BlobItems...)
+	}
+
+	return res, nil
+}
+
+func (b *Blob) downloadAll(ctx context.Context, blobClient *azureblob.Client) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	result, err := blobClient.DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob : %v", err)
+	}
+	defer result.Body.Close()
+
+	body, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob : %v", err)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,125 @@
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// Algorithm identifies the hash function used to verify object integrity end-to-end.
+// The zero value disables verification.
+type Algorithm string
+
+const (
+	SHA256 Algorithm = "sha256"
+	CRC32C Algorithm = "crc32c"
+)
+
+// ErrChecksumMismatch is returned when the downloaded data does not match the checksum stored on upload.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ErrUnsupportedAlgorithm is returned for an Algorithm that is neither SHA256 nor CRC32C.
+var ErrUnsupportedAlgorithm = errors.New("unsupported checksum algorithm")
+
+// MismatchError carries the expected and actual checksums of a failed verification.
+// It matches ErrChecksumMismatch with errors.Is.
+type MismatchError struct {
+	Algorithm Algorithm
+	Expected  string
+	Actual    string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s %s : expected %s, got %s", e.Algorithm, ErrChecksumMismatch, e.Expected, e.Actual)
+}
+
+func (e *MismatchError) Unwrap() error {
+	return ErrChecksumMismatch
+}
+
+// New returns a fresh hash.Hash for the algorithm.
+func (a Algorithm) New() (hash.Hash, error) {
+	switch a {
+	case SHA256:
+		return sha256.New(), nil
+	case CRC32C:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	default:
+		return nil, fmt.Errorf("%w : %q", ErrUnsupportedAlgorithm, string(a))
+	}
+}
+
+// MetadataKey is the user metadata key under which the checksum is stored alongside the object.
+func (a Algorithm) MetadataKey() string {
+	return "checksum_" + string(a)
+}
+
+// Sum reads r to the end and returns its base64 encoded checksum.
+func (a Algorithm) Sum(r io.Reader) (string, error) {
+	h, err := a.New()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+
+	return Encode(h), nil
+}
+
+// Encode returns the base64 encoded digest of h, the format used by S3 for its checksum fields.
+func Encode(h hash.Hash) string {
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Verify returns a *MismatchError when expected and actual differ.
+func Verify(a Algorithm, expected string, actual string) error {
+	if expected != actual {
+		return &MismatchError{Algorithm: a, Expected: expected, Actual: actual}
+	}
+
+	return nil
+}
+
+// Lookup finds the checksum stored in object metadata. Keys are compared case-insensitively
+// because providers canonicalize metadata header names differently.
+func Lookup(metadata map[string]string, a Algorithm) (string, bool) {
+	for k, v := range metadata {
+		if strings.EqualFold(k, a.MetadataKey()) {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// NewHashingWriterAt returns an io.WriterAt feeding writes into h before passing them to w. Writes must be
+// sequential, e.g. from a download with a concurrency of 1; an out of order write fails.
+func NewHashingWriterAt(w io.WriterAt, h hash.Hash) io.WriterAt {
+	return &hashingWriterAt{w: w, h: h}
+}
+
+// hashingWriterAt feeds sequential writes into a hash before passing them to the underlying io.WriterAt.
+type hashingWriterAt struct {
+	w      io.WriterAt
+	h      hash.Hash
+	offset int64
+}
+
+func (hw *hashingWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if off != hw.offset {
+		return 0, fmt.Errorf("unexpected write at offset %d, expected %d", off, hw.offset)
+	}
+
+	n, err := hw.w.WriteAt(p, off)
+	hw.h.Write(p[:n])
+	hw.offset += int64(n)
+
+	return n, err
+}
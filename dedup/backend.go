@@ -0,0 +1,82 @@
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/badfan/go-toolkit/aws/s3"
+	"github.com/badfan/go-toolkit/azure/blob"
+)
+
+// Backend is the object storage holding the deduplicated content.
+type Backend interface {
+	Put(ctx context.Context, key string, body []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// S3Backend stores content in an S3 bucket.
+type S3Backend struct {
+	client     s3.IS3Client
+	bucketName string
+}
+
+func NewS3Backend(client s3.IS3Client, bucketName string) *S3Backend {
+	return &S3Backend{client: client, bucketName: bucketName}
+}
+
+func (b *S3Backend) Put(ctx context.Context, key string, body []byte) error {
+	_, err := b.client.UploadObject(ctx, b.bucketName, key, bytes.NewReader(body))
+	return err
+}
+
+func (b *S3Backend) Get(ctx context.Context, key string) ([]byte, error) {
+	buf := manager.NewWriteAtBuffer(nil)
+	if err := b.client.DownloadObject(ctx, b.bucketName, key, buf); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (b *S3Backend) Delete(ctx context.Context, key string) error {
+	res, err := b.client.DeleteObjects(ctx, b.bucketName, []string{key})
+	if err != nil {
+		return err
+	}
+
+	// a bulk delete succeeds as a whole even when the key was not deleted
+	if len(res.Errors) > 0 {
+		e := res.Errors[0]
+		return fmt.Errorf("failed to delete object %s : %s : %s", key, aws.ToString(e.Code), aws.ToString(e.Message))
+	}
+
+	return nil
+}
+
+// BlobBackend stores content in an Azure Blob Storage container.
+type BlobBackend struct {
+	client        blob.IBlobClient
+	containerName string
+}
+
+func NewBlobBackend(client blob.IBlobClient, containerName string) *BlobBackend {
+	return &BlobBackend{client: client, containerName: containerName}
+}
+
+func (b *BlobBackend) Put(ctx context.Context, key string, body []byte) error {
+	_, err := b.client.UploadBlob(ctx, b.containerName, "", key, body)
+	return err
+}
+
+func (b *BlobBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	return b.client.DownloadBlob(ctx, b.containerName, "", key)
+}
+
+func (b *BlobBackend) Delete(ctx context.Context, key string) error {
+	_, err := b.client.DeleteBlob(ctx, b.containerName, "", key)
+	return err
+}
@@ -0,0 +1,321 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound is returned when no content is stored under a logical key.
+var ErrNotFound = errors.New("logical key not found")
+
+// ErrContentBeingCollected is returned when Put races with the garbage collection of identical content.
+// The call can be retried once the collection has finished.
+var ErrContentBeingCollected = errors.New("content is being garbage collected")
+
+// errUploadRequired signals that the content is not stored yet and has to be uploaded before linking.
+var errUploadRequired = errors.New("upload required")
+
+// Content is a unique piece of data stored in the backend under its SHA-256 hash.
+type Content struct {
+	Hash           string `gorm:"primaryKey;size:64"`
+	Size           int64
+	CreatedAt      time.Time
+	UnreferencedAt *time.Time `gorm:"index"`
+	Deleting       bool
+}
+
+func (Content) TableName() string {
+	return "dedup_contents"
+}
+
+// Reference links a logical key to the content it currently holds.
+type Reference struct {
+	Key       string `gorm:"primaryKey"`
+	Hash      string `gorm:"size:64;index;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (Reference) TableName() string {
+	return "dedup_references"
+}
+
+// Store is a content-addressed store that keeps a single copy of identical data. Logical keys are
+// mapped to content hashes in Postgres, and content is garbage collected once it has been
+// unreferenced for longer than the grace period.
+type Store struct {
+	backend     Backend
+	db          *gorm.DB
+	prefix      string
+	gracePeriod time.Duration
+}
+
+type StoreConfig struct {
+	// Prefix is prepended to the backend key of every content, e.g. "attachments/".
+	Prefix      string
+	GracePeriod time.Duration
+}
+
+func NewStore(backend Backend, db *gorm.DB, c StoreConfig) *Store {
+	return &Store{
+		backend:     backend,
+		db:          db,
+		prefix:      c.Prefix,
+		gracePeriod: c.GracePeriod,
+	}
+}
+
+// Migrate creates or updates the tables holding contents and references.
+func (s *Store) Migrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(&Content{}, &Reference{})
+}
+
+// Put stores body under a logical key and returns its content hash. The body is uploaded only
+// if identical content is not stored yet. Content previously held by the key is released.
+func (s *Store) Put(ctx context.Context, key string, body []byte) (string, error) {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	uploaded := false
+	for {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			content, err := lockContent(tx, hash)
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				if !uploaded {
+					return errUploadRequired
+				}
+
+				err = tx.Clauses(clause.OnConflict{DoNothing: true}).
+					Create(&Content{Hash: hash, Size: int64(len(body))}).Error
+				if err != nil {
+					return err
+				}
+			case err != nil:
+				return err
+			case content.Deleting:
+				return ErrContentBeingCollected
+			case content.UnreferencedAt != nil:
+				if err = tx.Model(content).Update("unreferenced_at", nil).Error; err != nil {
+					return err
+				}
+			}
+
+			return s.link(tx, key, hash)
+		})
+		if errors.Is(err, errUploadRequired) {
+			// uploading outside the transaction keeps row locks short, and re-uploading identical content is harmless
+			if err = s.backend.Put(ctx, s.objectKey(hash), body); err != nil {
+				return "", fmt.Errorf("failed to upload content : %v", err)
+			}
+			uploaded = true
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to put %s : %w", key, err)
+		}
+
+		return hash, nil
+	}
+}
+
+// Get returns the content stored under a logical key.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	hash, err := s.Hash(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := s.backend.Get(ctx, s.objectKey(hash))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download content : %v", err)
+	}
+
+	return body, nil
+}
+
+// Hash returns the content hash stored under a logical key.
+func (s *Store) Hash(ctx context.Context, key string) (string, error) {
+	var ref Reference
+	err := s.db.WithContext(ctx).First(&ref, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get reference : %v", err)
+	}
+
+	return ref.Hash, nil
+}
+
+// References returns the logical keys currently holding a content.
+func (s *Store) References(ctx context.Context, hash string) ([]string, error) {
+	var keys []string
+	err := s.db.WithContext(ctx).Model(&Reference{}).Where("hash = ?", hash).Pluck("key", &keys).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references : %v", err)
+	}
+
+	return keys, nil
+}
+
+// Delete removes a logical key. The content it held stays in the backend until it is garbage collected.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ref Reference
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ref, "key = ?", key).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get reference : %v", err)
+		}
+
+		if err = tx.Delete(&ref).Error; err != nil {
+			return fmt.Errorf("failed to delete reference : %v", err)
+		}
+
+		return release(tx, ref.Hash)
+	})
+}
+
+// CollectGarbage deletes content that has been unreferenced for longer than the grace period and
+// returns how many were deleted. Content is claimed in Postgres before it is removed from the backend,
+// so a concurrent Put of identical data either revives it first or waits for the collection to finish.
+func (s *Store) CollectGarbage(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-s.gracePeriod)
+
+	var hashes []string
+	err := s.db.WithContext(ctx).Model(&Content{}).
+		Where("unreferenced_at < ? OR deleting = ?", cutoff, true).
+		Pluck("hash", &hashes).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to list unreferenced content : %v", err)
+	}
+
+	var deleted int
+	for _, hash := range hashes {
+		claimed, err := s.claim(ctx, hash, cutoff)
+		if err != nil {
+			return deleted, err
+		}
+		if !claimed {
+			continue
+		}
+
+		if err = s.backend.Delete(ctx, s.objectKey(hash)); err != nil {
+			return deleted, fmt.Errorf("failed to delete content : %v", err)
+		}
+
+		if err = s.db.WithContext(ctx).Delete(&Content{Hash: hash}).Error; err != nil {
+			return deleted, fmt.Errorf("failed to delete content record : %v", err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// claim marks a content as being deleted if it is still unreferenced past the cutoff.
+func (s *Store) claim(ctx context.Context, hash string, cutoff time.Time) (bool, error) {
+	claimed := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		content, err := lockContent(tx, hash)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !content.Deleting {
+			if content.UnreferencedAt == nil || content.UnreferencedAt.After(cutoff) {
+				return nil
+			}
+
+			var refs int64
+			if err = tx.Model(&Reference{}).Where("hash = ?", hash).Count(&refs).Error; err != nil {
+				return err
+			}
+			if refs > 0 {
+				return nil
+			}
+
+			if err = tx.Model(content).Update("deleting", true).Error; err != nil {
+				return err
+			}
+		}
+
+		claimed = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim content : %v", err)
+	}
+
+	return claimed, nil
+}
+
+// link points a logical key to a content, releasing the content it previously held.
+func (s *Store) link(tx *gorm.DB, key string, hash string) error {
+	var ref Reference
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ref, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return tx.Create(&Reference{Key: key, Hash: hash}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	if ref.Hash == hash {
+		return nil
+	}
+
+	previous := ref.Hash
+	if err = tx.Model(&ref).Update("hash", hash).Error; err != nil {
+		return err
+	}
+
+	return release(tx, previous)
+}
+
+// release starts the grace period of a content once no logical key references it anymore.
+func release(tx *gorm.DB, hash string) error {
+	content, err := lockContent(tx, hash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var refs int64
+	if err = tx.Model(&Reference{}).Where("hash = ?", hash).Count(&refs).Error; err != nil {
+		return err
+	}
+	if refs > 0 {
+		return nil
+	}
+
+	return tx.Model(content).Update("unreferenced_at", time.Now()).Error
+}
+
+func lockContent(tx *gorm.DB, hash string) (*Content, error) {
+	var content Content
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&content, "hash = ?", hash).Error; err != nil {
+		return nil, err
+	}
+
+	return &content, nil
+}
+
+func (s *Store) objectKey(hash string) string {
+	return s.prefix + "sha256/" + hash[:2] + "/" + hash
+}
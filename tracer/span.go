@@ -98,3 +98,21 @@ func AddTraceIDToContext(ctx context.Context) (context.Context, error) {
 type SpanCustomiser interface {
 	customise() []trace.SpanStartOption
 }
+
+// ClientSpan customises a span as an outgoing call to a remote service such as
+// a storage account or a database. Tags are added to the span when it starts.
+type ClientSpan struct {
+	Tags map[string]string
+}
+
+func (c ClientSpan) customise() []trace.SpanStartOption {
+	list := make([]attribute.KeyValue, 0, len(c.Tags))
+	for k, v := range c.Tags {
+		list = append(list, attribute.Key(k).String(v))
+	}
+
+	return []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(list...),
+	}
+}
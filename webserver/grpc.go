@@ -8,17 +8,19 @@ import (
 	"google.golang.org/grpc"
 )
 
-func NewGRPCServer() (*grpc.Server, net.Listener, error) {
+// NewGRPCServer creates a gRPC server traced with OpenTelemetry. Options can add interceptors, e.g.
+// grpc.ChainUnaryInterceptor(auth.UnaryServerInterceptor[Claims](...)), which run after the tracing ones.
+func NewGRPCServer(opts ...grpc.ServerOption) (*grpc.Server, net.Listener, error) {
 	listener, err := net.Listen(viper.GetString("rpc_server_network"),
 		viper.GetString("rpc_server_host")+":"+viper.GetString("rpc_server_port"))
 	if err != nil {
 		return nil, nil, err
 	}
 
-	rpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
-		grpc.StreamInterceptor(otelgrpc.StreamServerInterceptor()),
-	)
+	rpcServer := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(otelgrpc.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(otelgrpc.StreamServerInterceptor()),
+	}, opts...)...)
 
 	return rpcServer, listener, nil
 }